@@ -3,10 +3,14 @@ package app
 import(
   "github.com/jasonlvhit/gocron"
   "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/library"
 )
 
 func loadJobs(){
   gocron.Every(30).Seconds().Do(models.JobRenewAuth)
+  gocron.Every(10).Minutes().Do(library.JobScan)
+  gocron.Every(15).Seconds().Do(models.JobDeliverWebhooks)
+  go library.JobScan()
 
   gocron.Start()
 }
@@ -4,27 +4,98 @@ import(
   "github.com/samarudge/jukebox/controllers"
   "github.com/gin-gonic/gin"
   "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
 )
 
+var apiRoutes []controllers.ApiRoute
+
 func loadRoutes(router *gin.Engine){
   router.GET("/", helpers.RequireRoom(), controllers.Index)
   router.GET("/auth/login", controllers.AuthLogin)
   router.GET("/auth/callback/:providerName", controllers.AuthCallback)
   router.GET("/auth/logout", controllers.AuthLogout)
 
+  router.GET("/music/search", helpers.RequireAuth(), controllers.MusicSearch)
+  router.GET("/music/playlists", helpers.RequireAuth(), controllers.PlaylistList)
+  router.GET("/music/playlists/:playlistId", helpers.RequireAuth(), controllers.PlaylistView)
+  router.POST("/music/playlists/:playlistId/queue", helpers.RequireAuth(), controllers.PlaylistQueue)
+
   router.GET("/rooms", controllers.RoomList)
   router.POST("/rooms", helpers.RequireAuth(), controllers.RoomCreate)
+  router.GET("/rooms/:roomId", controllers.RoomView)
   router.POST("/rooms/:roomId/join", helpers.RequireAuth(), controllers.RoomJoin)
+  router.POST("/rooms/:roomId/switch", helpers.RequireAuth(), controllers.RoomSwitch)
+  router.POST("/rooms/:roomId/leave", helpers.RequireAuth(), controllers.RoomLeave)
+  router.GET("/rooms/:roomId/stats", controllers.RoomStats)
+  router.GET("/rooms/:roomId/export", helpers.RequireAuth(), controllers.RoomExportView)
+  router.POST("/rooms/:roomId/export", helpers.RequireAuth(), controllers.RoomExport)
+  router.POST("/rooms/:roomId/invites", helpers.RequireAuth(), controllers.RoomInvite)
+  router.POST("/rooms/:roomId/invites/revoke", helpers.RequireAuth(), controllers.RoomInviteRevoke)
+  router.POST("/rooms/:roomId/webhooks", helpers.RequireAuth(), controllers.RoomWebhookCreate)
+  router.POST("/rooms/:roomId/webhooks/:webhookId/delete", helpers.RequireAuth(), controllers.RoomWebhookDelete)
+  router.POST("/rooms/:roomId/members/:userId/kick", helpers.RequireAuth(), controllers.RoomKick)
+  router.POST("/rooms/:roomId/members/:userId/role", helpers.RequireAuth(), controllers.RoomSetRole)
+  router.POST("/rooms/:roomId/skip", helpers.RequireAuth(), controllers.RoomSkip)
+  router.POST("/rooms/:roomId/settings", helpers.RequireAuth(), controllers.RoomSettings)
+  router.GET("/rooms/:roomId/player", helpers.RequireAuth(), controllers.PlayerView)
+  router.POST("/rooms/:roomId/player", helpers.RequireAuth(), controllers.PlayerUpdate)
+  router.POST("/rooms/:roomId/player/control", helpers.RequireAuth(), controllers.PlayerControl)
+  router.GET("/rooms/:roomId/queue", controllers.QueueView)
+  router.GET("/rooms/:roomId/stream", controllers.RoomStream)
+  router.GET("/rooms/:roomId/events", controllers.RoomEvents)
+  router.GET("/rooms/:roomId/socket", helpers.RequireAuth(), controllers.RoomSocket)
+  router.POST("/rooms/:roomId/queue", helpers.RequireAuth(), controllers.QueueAdd)
+  router.POST("/rooms/:roomId/queue/:entryId/remove", helpers.RequireAuth(), controllers.QueueRemove)
+  router.POST("/rooms/:roomId/queue/:entryId/move", helpers.RequireAuth(), controllers.QueueMove)
+  router.POST("/rooms/:roomId/queue/:entryId/vote", helpers.RequireAuth(), controllers.QueueVote)
+
+  router.POST("/slack/commands", controllers.SlackCommand)
+  router.POST("/slack/actions", controllers.SlackAction)
 
   adminRoutes := router.Group("/")
   adminRoutes.Use(helpers.RequireAdmin())
   adminRoutes.GET("/admin", controllers.AdminIndex)
+  adminRoutes.POST("/admin/library/scan", controllers.AdminLibraryScan)
+  adminRoutes.POST("/admin/webhooks/:deliveryId/retry", controllers.AdminWebhookRetry)
   adminRoutes.GET("/users", controllers.UserList)
   adminRoutes.GET("/auths", controllers.AuthList)
 
+  api := router.Group(helpers.ApiPrefix)
+  api.Use(helpers.JsonForm())
+  // Recorded so the tests can check each one is in the OpenAPI document
+  apiRoute := func(method string, path string, handlers ...gin.HandlerFunc){
+    apiRoutes = append(apiRoutes, controllers.ApiRoute{Method: method, Path: path})
+    api.Handle(method, path, handlers...)
+  }
+  read := helpers.RequireScope(models.ScopeRead)
+  queue := helpers.RequireScope(models.ScopeQueue)
+  control := helpers.RequireScope(models.ScopePlayer)
+  admin := helpers.RequireScope(models.ScopeAdmin)
+  apiRoute("GET", "/openapi.json", controllers.ApiSpec)
+  apiRoute("GET", "/me", helpers.RequireAuth(), read, controllers.ApiMe)
+  apiRoute("GET", "/rooms", read, controllers.ApiRoomList)
+  apiRoute("POST", "/rooms", helpers.RequireAuth(), queue, controllers.RoomCreate)
+  apiRoute("GET", "/rooms/:roomId", read, controllers.ApiRoomView)
+  apiRoute("POST", "/rooms/:roomId/join", helpers.RequireAuth(), queue, controllers.RoomJoin)
+  apiRoute("POST", "/rooms/:roomId/leave", helpers.RequireAuth(), queue, controllers.RoomLeave)
+  apiRoute("POST", "/rooms/:roomId/skip", helpers.RequireAuth(), queue, controllers.RoomSkip)
+  apiRoute("GET", "/rooms/:roomId/queue", read, controllers.ApiQueue)
+  apiRoute("POST", "/rooms/:roomId/queue", helpers.RequireAuth(), queue, controllers.QueueAdd)
+  apiRoute("POST", "/rooms/:roomId/queue/:entryId/remove", helpers.RequireAuth(), queue, controllers.QueueRemove)
+  apiRoute("POST", "/rooms/:roomId/queue/:entryId/move", helpers.RequireAuth(), queue, controllers.QueueMove)
+  apiRoute("POST", "/rooms/:roomId/queue/:entryId/vote", helpers.RequireAuth(), queue, controllers.QueueVote)
+  apiRoute("GET", "/rooms/:roomId/player", helpers.RequireAuth(), read, controllers.ApiPlayerStatus)
+  apiRoute("POST", "/rooms/:roomId/player/control", helpers.RequireAuth(), control, controllers.PlayerControl)
+  apiRoute("GET", "/users", helpers.RequireAdmin(), admin, controllers.ApiUserList)
+  apiRoute("GET", "/users/:userId", helpers.AuthorizedUser(), controllers.UserContext, read, controllers.ApiUserInfo)
+  apiRoute("POST", "/users/:userId", helpers.AuthorizedUser(), controllers.UserContext, admin, controllers.UserUpdate)
+
   userRoutes := router.Group("/users")
   userRoutes.Use(helpers.AuthorizedUser())
   userRoutes.Use(controllers.UserContext)
   userRoutes.GET("/:userId", controllers.UserInfo)
+  userRoutes.GET("/:userId/stats", controllers.UserStats)
+  userRoutes.POST("/:userId/tokens", controllers.UserTokenCreate)
+  userRoutes.POST("/:userId/tokens/:tokenId/revoke", controllers.UserTokenRevoke)
   userRoutes.POST("/:userId", controllers.UserUpdate)
 }
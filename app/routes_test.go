@@ -0,0 +1,20 @@
+package app
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/controllers"
+  "testing"
+)
+
+func TestApiRoutesDocumented(t *testing.T){
+  gin.SetMode(gin.TestMode)
+  apiRoutes = nil
+  loadRoutes(gin.New())
+
+  if len(apiRoutes) == 0{
+    t.Fatal("No API routes were registered")
+  }
+  if err := controllers.CheckApiSpec(apiRoutes); err != nil{
+    t.Error(err)
+  }
+}
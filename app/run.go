@@ -4,18 +4,14 @@ import(
   "github.com/gin-gonic/gin"
   "github.com/samarudge/jukebox/helpers"
   "github.com/samarudge/jukebox/models"
-  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/player"
 )
 
 var router = gin.New()
 
 func Start(bind string){
 
-  d := db.Db()
-  d.AutoMigrate(&models.User{})
-  d.AutoMigrate(&models.Oauth2{})
-  d.AutoMigrate(&models.Room{})
-  d.AutoMigrate(&models.Spotify{})
+  models.Migrate()
 
   router.Use(helpers.Logger())
   router.Use(gin.Recovery())
@@ -26,6 +22,7 @@ func Start(bind string){
 
   loadRoutes(router)
   loadJobs()
+  player.StartAll()
 
   router.Run(bind)
 }
@@ -7,8 +7,13 @@ import(
   log "github.com/Sirupsen/logrus"
   "encoding/json"
   "time"
+  "github.com/samarudge/jukebox/spotify"
 )
 
+// Needed to export room history, accounts linked before these were added must re-link
+var SpotifyPlaylistModifyScopes = []string{"playlist-modify-public", "playlist-modify-private"}
+var SpotifyPlaybackScopes = []string{"user-read-playback-state", "user-modify-playback-state"}
+
 type Spotify struct{
   BaseProvider
 }
@@ -17,7 +22,8 @@ func NewSpotify(p BaseProvider, _ map[interface{}]interface{}) *Spotify{
   p.Name =        "Spotify"
   p.AuthURL =     "https://accounts.spotify.com/authorize?show_dialog=true"
   p.TokenURL =    "https://accounts.spotify.com/api/token"
-  p.Scopes =      []string{"playlist-read-private", "playlist-read-collaborative", "user-follow-read", "user-library-read", "user-read-private", "user-read-email"}
+  p.Scopes =      append([]string{"playlist-read-private", "playlist-read-collaborative", "user-follow-read", "user-library-read", "user-read-private", "user-read-email"}, SpotifyPlaylistModifyScopes...)
+  p.Scopes =      append(p.Scopes, SpotifyPlaybackScopes...)
   p.ReauthEvery = time.Minute*30
 
   return &Spotify{
@@ -30,7 +36,7 @@ func (p *Spotify) GetUserData(token *oauth2.Token) (string, UserData, error){
   user := UserData{}
   var ProviderId string
 
-  rsp, err := client.Get("https://api.spotify.com/v1/me")
+  rsp, err := client.Get(spotify.NewClient(client).Url("/me", nil))
   if err != nil{
     return ProviderId, user, err
   }
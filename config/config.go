@@ -7,6 +7,10 @@ import(
   "io/ioutil"
   "github.com/samarudge/jukebox/db"
   "github.com/samarudge/jukebox/auth"
+  "github.com/samarudge/jukebox/spotify"
+  "github.com/samarudge/jukebox/library"
+  "github.com/samarudge/jukebox/player"
+  "github.com/samarudge/jukebox/slack"
   "net/url"
   "fmt"
 )
@@ -17,6 +21,20 @@ type config struct{
   Auth      struct{
     Configured_providers       []string
   }
+  Spotify   struct{
+    Api_url   string
+  }
+  Library   struct{
+    Path      string
+  }
+  Mpd       struct{
+    Password  string
+  }
+  Slack     struct{
+    Signing_secret  string
+    Bot_token       string
+    Api_url         string
+  }
 }
 
 var Config config
@@ -93,6 +111,19 @@ func Initialize(filePath string){
 
   auth.ConfiguredProviders = Config.Auth.Configured_providers
 
+  if Config.Spotify.Api_url != ""{
+    spotify.ApiUrl = Config.Spotify.Api_url
+  }
+
+  library.Root = Config.Library.Path
+  player.MpdPassword = Config.Mpd.Password
+
+  slack.SigningSecret = Config.Slack.Signing_secret
+  slack.BotToken = Config.Slack.Bot_token
+  if Config.Slack.Api_url != ""{
+    slack.ApiUrl = Config.Slack.Api_url
+  }
+
   //Open DB
   db.OpenDB("./storage.db")
 }
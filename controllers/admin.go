@@ -4,6 +4,7 @@ import(
   "github.com/gin-gonic/gin"
   "github.com/samarudge/jukebox/helpers"
   "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/library"
 )
 
 func AdminIndex(c *gin.Context){
@@ -11,5 +12,20 @@ func AdminIndex(c *gin.Context){
   systemSpotify.LoadSystem()
   helpers.Render(c, "admin/index.html", gin.H{
     "systemSpotify": systemSpotify,
+    "mostSkipped": models.MostSkipped(10),
+    "libraryConfigured": library.Configured(),
+    "libraryRoot": library.Root,
+    "libraryTracks": models.LibraryTrackCount(),
+    "webhookDeliveries": models.RecentDeliveries(50),
   })
 }
+
+func AdminLibraryScan(c *gin.Context){
+  if !library.Configured(){
+    helpers.Send400(c, "No library path has been configured")
+    return
+  }
+
+  go library.JobScan()
+  c.Redirect(302, "/admin")
+}
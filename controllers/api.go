@@ -0,0 +1,261 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/player"
+  "fmt"
+  "time"
+)
+
+/*
+  JSON versions of the pages, the actions themselves are shared with the
+  site and answer API requests through helpers.Respond
+*/
+
+type apiUser struct{
+  ID          uint      `json:"id"`
+  Name        string    `json:"name"`
+  Username    string    `json:"username"`
+  Photo       string    `json:"photo"`
+  IsAdmin     bool      `json:"isAdmin"`
+  HasSpotify  bool      `json:"hasSpotify"`
+  RoomID      uint64    `json:"roomId"`
+  LastSeen    time.Time `json:"lastSeen"`
+}
+
+type apiRoom struct{
+  ID                uint    `json:"id"`
+  Name              string  `json:"name"`
+  CreatorID         uint64  `json:"creatorId"`
+  Private           bool    `json:"private"`
+  Backend           string  `json:"backend"`
+  QueueMode         string  `json:"queueMode"`
+  SkipPercent       int     `json:"skipPercent"`
+  MaxQueuedPerUser  int     `json:"maxQueuedPerUser"`
+  MaxTrackSeconds   int     `json:"maxTrackSeconds"`
+  BlockExplicit     bool    `json:"blockExplicit"`
+  RepeatWindowHours int     `json:"repeatWindowHours"`
+  AutoDJ            string  `json:"autoDJ"`
+}
+
+// Anyone who can see the room sees its members, so no usernames, they're emails for Google logins
+type apiMember struct{
+  ID    uint    `json:"id"`
+  Name  string  `json:"name"`
+  Photo string  `json:"photo"`
+  Role  string  `json:"role"`
+}
+
+type apiRoomDetail struct{
+  apiRoom
+  NowPlaying      *models.EntryData   `json:"nowPlaying"`
+  Queue           []models.EntryData  `json:"queue"`
+  Members         []apiMember         `json:"members"`
+  SkipVotes       int                 `json:"skipVotes"`
+  SkipVotesNeeded int                 `json:"skipVotesNeeded"`
+}
+
+type apiRoomList struct{
+  Rooms []apiRoom `json:"rooms"`
+}
+
+type apiUserList struct{
+  Users []apiUser `json:"users"`
+}
+
+type apiQueue struct{
+  NowPlaying  *models.EntryData   `json:"nowPlaying"`
+  Queue       []models.EntryData  `json:"queue"`
+}
+
+type apiVoteResult struct{
+  Entry models.EntryData  `json:"entry"`
+  // The caller's vote after the change, -1, 0 or 1
+  Vote  int               `json:"vote"`
+}
+
+type apiSkipResult struct{
+  // False when the vote was counted but more are needed
+  Skipped     bool              `json:"skipped"`
+  NowPlaying  *models.EntryData `json:"nowPlaying"`
+}
+
+type apiPlayerStatus struct{
+  Configured  bool    `json:"configured"`
+  Running     bool    `json:"running"`
+  Playing     bool    `json:"playing"`
+  TrackUri    string  `json:"trackUri"`
+  PositionMs  int64   `json:"positionMs"`
+  DurationMs  int64   `json:"durationMs"`
+}
+
+func userJson(u models.User) apiUser{
+  a := u.Auth()
+  return apiUser{
+    ID: u.ID,
+    Name: a.Name,
+    Username: a.Username,
+    Photo: a.ProfilePhoto,
+    IsAdmin: u.IsAdmin,
+    HasSpotify: u.HasSpotify(),
+    RoomID: u.RoomID,
+    LastSeen: u.LastSeen,
+  }
+}
+
+func roomJson(r models.Room) apiRoom{
+  return apiRoom{
+    ID: r.ID,
+    Name: r.Name,
+    CreatorID: r.CreatorID,
+    Private: r.Private,
+    Backend: r.BackendName(),
+    QueueMode: r.QueueModeName(),
+    SkipPercent: r.SkipPercent(),
+    MaxQueuedPerUser: r.MaxQueuedPerUser,
+    MaxTrackSeconds: r.MaxTrackSeconds,
+    BlockExplicit: r.BlockExplicit,
+    RepeatWindowHours: r.RepeatWindowHours,
+    AutoDJ: r.AutoDJ,
+  }
+}
+
+func roomDetailJson(r models.Room) apiRoomDetail{
+  detail := apiRoomDetail{
+    apiRoom: roomJson(r),
+    NowPlaying: r.NowPlayingData(),
+    Queue: r.QueueData(),
+    Members: []apiMember{},
+    SkipVotesNeeded: r.SkipVotesNeeded(),
+  }
+  if current, playing := r.NowPlaying(); playing{
+    detail.SkipVotes = current.SkipVotes()
+  }
+  for _, u := range r.MemberList(){
+    a := u.Auth()
+    detail.Members = append(detail.Members, apiMember{
+      ID: u.ID,
+      Name: a.Name,
+      Photo: a.ProfilePhoto,
+      Role: r.RoleOf(u),
+    })
+  }
+  return detail
+}
+
+func statusJson(room models.Room) (apiPlayerStatus, error){
+  status := apiPlayerStatus{
+    Configured: room.PlayerConfigured(),
+    Running: player.Running(room),
+  }
+  if !status.Configured{
+    return status, nil
+  }
+
+  backend, err := player.NewBackend(room)
+  if err != nil{
+    return status, err
+  }
+  s, err := backend.Status()
+  if err != nil{
+    return status, err
+  }
+
+  status.Playing = s.Playing
+  status.TrackUri = s.TrackUri
+  status.PositionMs = int64(s.Position/time.Millisecond)
+  status.DurationMs = int64(s.Duration/time.Millisecond)
+  return status, nil
+}
+
+func queueJson(r models.Room) apiQueue{
+  return apiQueue{
+    NowPlaying: r.NowPlayingData(),
+    Queue: r.QueueData(),
+  }
+}
+
+func optionalUser(c *gin.Context) models.User{
+  u := models.User{}
+  userInterface, _ := c.Get("authUser")
+  if userInterface != nil{
+    u = userInterface.(models.User)
+  }
+  return u
+}
+
+func ApiRoomList(c *gin.Context){
+  result := apiRoomList{Rooms: []apiRoom{}}
+  for _, r := range models.VisibleRooms(optionalUser(c)){
+    result.Rooms = append(result.Rooms, roomJson(r))
+  }
+  c.JSON(200, result)
+}
+
+func ApiRoomView(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+  if !checkInvite(c, room, optionalUser(c), c.Query("invite")){
+    return
+  }
+
+  c.JSON(200, roomDetailJson(room))
+}
+
+func ApiQueue(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+  if !checkInvite(c, room, optionalUser(c), c.Query("invite")){
+    return
+  }
+
+  c.JSON(200, queueJson(room))
+}
+
+func ApiPlayerStatus(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.CanModerate(u){
+    helpers.Send403(c, "Only the room's owners and DJs can control playback")
+    return
+  }
+
+  status, err := statusJson(room)
+  if err != nil{
+    helpers.Send500(c, fmt.Sprintf("%s (%s)", "Player error", err))
+    return
+  }
+  c.JSON(200, status)
+}
+
+func ApiMe(c *gin.Context){
+  c.JSON(200, userJson(c.MustGet("authUser").(models.User)))
+}
+
+func ApiUserInfo(c *gin.Context){
+  c.JSON(200, userJson(c.MustGet("contextUser").(models.User)))
+}
+
+func ApiUserList(c *gin.Context){
+  d := db.Db()
+
+  var users []models.User
+  d.Order("name").Find(&users)
+
+  result := apiUserList{Users: []apiUser{}}
+  for _, u := range users{
+    result.Users = append(result.Users, userJson(u))
+  }
+  c.JSON(200, result)
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/events"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+  "golang.org/x/net/websocket"
+  log "github.com/Sirupsen/logrus"
+  "encoding/json"
+  "fmt"
+  "io"
+  "net/http"
+  "net/url"
+  "strconv"
+  "time"
+)
+
+// Comment lines keep proxies from closing idle event streams
+var eventsHeartbeat = time.Second*15
+
+func snapshotEvent(room models.Room, lastId uint64) events.Event{
+  return events.Event{
+    ID: lastId,
+    Type: "snapshot",
+    RoomID: room.ID,
+    Time: time.Now().UTC(),
+    Data: room.Snapshot(),
+  }
+}
+
+func sameOrigin(config *websocket.Config, req *http.Request) error{
+  /*
+    The socket is authenticated by cookie, so other sites mustn't be able to
+    open one on a visitor's behalf
+  */
+  origin, err := url.Parse(req.Header.Get("Origin"))
+  if err != nil || origin.Host != req.Host{
+    return fmt.Errorf("Cross origin websocket refused")
+  }
+  return nil
+}
+
+func RoomSocket(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.VisibleTo(u){
+    helpers.Send403(c, "This room is private")
+    return
+  }
+
+  server := websocket.Server{
+    Handshake: sameOrigin,
+    Handler: func(ws *websocket.Conn){
+      roomSocket(ws, room, u)
+    },
+  }
+  server.ServeHTTP(c.Writer, c.Request)
+}
+
+func roomSocket(ws *websocket.Conn, room models.Room, u models.User){
+  defer ws.Close()
+
+  sub, _, lastId, _ := events.SubscribeFrom(room.ID, u.ID, 0)
+  defer events.Unsubscribe(sub)
+
+  log.WithFields(log.Fields{
+    "roomId": room.ID,
+    "userId": u.ID,
+  }).Debug("Room socket connected")
+
+  err := websocket.JSON.Send(ws, snapshotEvent(room, lastId))
+  if err != nil{
+    return
+  }
+
+  // Clients don't send anything, reading just tells us when they go away
+  closed := make(chan bool)
+  go func(){
+    var discard string
+    for websocket.Message.Receive(ws, &discard) == nil{
+    }
+    close(closed)
+  }()
+
+  for{
+    select{
+    case <-closed:
+      return
+    case e, open := <-sub.Events:
+      if !open{
+        // Dropped for falling behind or leaving the room, the client will reconnect
+        return
+      }
+      if websocket.JSON.Send(ws, e) != nil{
+        return
+      }
+    }
+  }
+}
+
+func writeEvent(w io.Writer, e events.Event) error{
+  data, err := json.Marshal(e)
+  if err != nil{
+    return err
+  }
+  _, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+  return err
+}
+
+func RoomEvents(c *gin.Context){
+  /*
+    The same events as the socket as a plain HTTP stream. Clients that send
+    Last-Event-ID get what they missed, or a new snapshot if it is too old
+  */
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := models.User{}
+  userInterface, _ := c.Get("authUser")
+  if userInterface != nil{
+    u = userInterface.(models.User)
+  }
+  if !checkInvite(c, room, u, c.Query("invite")){
+    return
+  }
+
+  after, _ := strconv.ParseUint(c.Request.Header.Get("Last-Event-ID"), 10, 64)
+  sub, missed, lastId, complete := events.SubscribeFrom(room.ID, u.ID, after)
+  defer events.Unsubscribe(sub)
+
+  c.Header("Content-Type", "text/event-stream")
+  c.Header("Cache-Control", "no-cache")
+  c.Header("X-Accel-Buffering", "no")
+  c.Status(200)
+  c.Writer.WriteHeaderNow()
+
+  log.WithFields(log.Fields{
+    "roomId": room.ID,
+    "after": after,
+    "missed": len(missed),
+    "complete": complete,
+  }).Debug("Room event stream connected")
+
+  if !complete{
+    missed = []events.Event{snapshotEvent(room, lastId)}
+  }
+  for _, e := range missed{
+    if writeEvent(c.Writer, e) != nil{
+      return
+    }
+  }
+  c.Writer.Flush()
+
+  closed := c.Writer.CloseNotify()
+  heartbeat := time.NewTicker(eventsHeartbeat)
+  defer heartbeat.Stop()
+
+  for{
+    select{
+    case <-closed:
+      return
+    case <-heartbeat.C:
+      if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil{
+        return
+      }
+    case e, open := <-sub.Events:
+      if !open{
+        return
+      }
+      if writeEvent(c.Writer, e) != nil{
+        return
+      }
+    }
+    c.Writer.Flush()
+  }
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+  "fmt"
+  "time"
+)
+
+const exportDateFormat = "2006-01-02"
+
+func canExportToSystem(room models.Room, u models.User) bool{
+  return room.IsOwner(u) || u.IsAdmin
+}
+
+func RoomExportView(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.VisibleTo(u){
+    helpers.Send403(c, "This room is private")
+    return
+  }
+
+  system := models.Spotify{}
+  system.LoadSystem()
+
+  now := time.Now().UTC()
+  helpers.Render(c, "rooms/export.html", gin.H{
+    "room": room,
+    "from": now.AddDate(0, 0, -7).Format(exportDateFormat),
+    "to": now.Format(exportDateFormat),
+    "name": fmt.Sprintf("%s, week of %s", room.Name, now.AddDate(0, 0, -7).Format("Jan 2 2006")),
+    "exports": room.Exports(),
+    "hasSpotify": u.HasSpotify(),
+    "needsRelink": u.SpotifyNeedsRelink(),
+    "canExportSystem": canExportToSystem(room, u) && system.Exists(),
+    "systemNeedsRelink": system.Exists() && !system.CanModifyPlaylists(),
+  })
+}
+
+func RoomExport(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.VisibleTo(u){
+    helpers.Send403(c, "This room is private")
+    return
+  }
+
+  from, err := time.Parse(exportDateFormat, c.PostForm("from"))
+  if err != nil{
+    helpers.Send400(c, "Give a start date as YYYY-MM-DD")
+    return
+  }
+  to, err := time.Parse(exportDateFormat, c.PostForm("to"))
+  if err != nil{
+    helpers.Send400(c, "Give an end date as YYYY-MM-DD")
+    return
+  }
+  // The end date is inclusive
+  to = to.AddDate(0, 0, 1)
+  if !from.Before(to){
+    helpers.Send400(c, "The start date must be before the end date")
+    return
+  }
+
+  name := c.PostForm("name")
+  if name == ""{
+    helpers.Send400(c, "Give the playlist a name")
+    return
+  }
+
+  var s models.Spotify
+  if c.PostForm("account") == "system"{
+    if !canExportToSystem(room, u){
+      helpers.Send403(c, "Only the room's owners can export to the system Spotify account")
+      return
+    }
+    s.LoadSystem()
+    if !s.Exists(){
+      helpers.Send500(c, "The system Spotify account has not been configured")
+      return
+    }
+  } else {
+    if !u.HasSpotify(){
+      helpers.Send403(c, "Link your Spotify account to export playlists to it")
+      return
+    }
+    s = u.GetSpotify()
+  }
+
+  if !s.CanModifyPlaylists(){
+    helpers.Send403(c, "That Spotify account was linked before playlist exports were added, re-link it to allow creating playlists")
+    return
+  }
+
+  _, err = room.Export(s, u, name, from, to)
+  if err != nil{
+    helpers.Send500(c, fmt.Sprintf("%s (%s)", "Error exporting playlist", err))
+    return
+  }
+
+  c.Redirect(302, room.Url() + "/export")
+}
@@ -3,8 +3,12 @@ package controllers
 import(
   "github.com/gin-gonic/gin"
   "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
 )
 
 func Index(c *gin.Context){
-  helpers.Render(c, "index.html", gin.H{})
+  room := c.MustGet("currentRoom").(models.Room)
+  helpers.Render(c, "index.html", gin.H{
+    "queue": queueRows(c, room, room.ProjectedQueue()),
+  })
 }
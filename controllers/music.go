@@ -0,0 +1,83 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/library"
+  "net/url"
+  "strconv"
+  "fmt"
+)
+
+const searchPageSize = 10
+
+func searchPageLink(query string, page int) string{
+  u := url.URL{}
+  u.Path = "/music/search"
+  q := u.Query()
+  q.Set("q", query)
+  q.Set("page", strconv.Itoa(page))
+  u.RawQuery = q.Encode()
+  return u.String()
+}
+
+func MusicSearch(c *gin.Context){
+  query := c.DefaultQuery("q", "")
+  page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+  if err != nil || page < 1{
+    page = 1
+  }
+
+  if len(query) == 0{
+    helpers.Render(c, "music/search.html", gin.H{})
+    return
+  }
+
+  s := models.Spotify{}
+  s.LoadSystem()
+  if !s.Exists() && !library.Configured(){
+    helpers.Send500(c, "The system Spotify account has not been configured")
+    return
+  }
+
+  offset := (page-1)*searchPageSize
+  obj := gin.H{
+    "query": query,
+    "page": page,
+  }
+  hasNext := false
+  empty := true
+
+  if s.Exists(){
+    results, err := s.Client().Search(query, []string{"track", "album", "artist"}, searchPageSize, offset)
+    if err != nil{
+      helpers.Send500(c, fmt.Sprintf("%s (%s)", "Error searching Spotify", err))
+      return
+    }
+    obj["results"] = results
+    hasNext = results.HasNext()
+    empty = results.Empty()
+  }
+
+  if library.Configured(){
+    tracks, total := models.SearchLibrary(query, searchPageSize, offset)
+    obj["libraryTracks"] = tracks
+    obj["libraryConfigured"] = true
+    hasNext = hasNext || offset + searchPageSize < total
+    empty = empty && len(tracks) == 0
+  }
+
+  obj["empty"] = empty
+  if room, inRoom := c.Get("currentRoom"); inRoom{
+    obj["queueUrl"] = room.(models.Room).Url() + "/queue"
+  }
+  if page > 1{
+    obj["prevLink"] = searchPageLink(query, page-1)
+  }
+  if hasNext{
+    obj["nextLink"] = searchPageLink(query, page+1)
+  }
+
+  helpers.Render(c, "music/search.html", obj)
+}
@@ -0,0 +1,338 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+  "fmt"
+  "reflect"
+  "regexp"
+  "sort"
+  "strings"
+  "time"
+)
+
+/*
+  The OpenAPI document is built from apiOperations and the same structs the
+  handlers answer with, so the schemas can't drift from the responses.
+  The app package's tests check every API route has an operation here
+*/
+
+// Registered by app/routes.go, paths are relative to helpers.ApiPrefix
+type ApiRoute struct{
+  Method  string
+  Path    string
+}
+
+type apiOperation struct{
+  Method    string
+  Path      string
+  Summary   string
+  // Token scope needed, empty when the route is public
+  Scope     string
+  // Needs a signed in user, otherwise signing in is optional
+  Auth      bool
+  Query     []string
+  Request   interface{}
+  Response  interface{}
+}
+
+type apiErrorDetail struct{
+  Status  int     `json:"status"`
+  Title   string  `json:"title"`
+  Message string  `json:"message"`
+}
+
+type apiError struct{
+  Error apiErrorDetail  `json:"error"`
+}
+
+type apiRoomCreateRequest struct{
+  RoomName  string  `json:"roomName" binding:"required"`
+}
+
+type apiJoinRequest struct{
+  Invite  string  `json:"invite" doc:"Invite token, needed for private rooms"`
+}
+
+type apiQueueAddRequest struct{
+  TrackUri  string  `json:"trackUri" binding:"required" doc:"spotify:track:... or local:... URI, the details are looked up"`
+}
+
+type apiDirectionRequest struct{
+  Direction string  `json:"direction" binding:"required" enum:"up,down"`
+}
+
+type apiPlayerControlRequest struct{
+  Action  string  `json:"action" binding:"required" enum:"pause,resume,volume"`
+  Volume  int     `json:"volume" doc:"0 to 100, needed when action is volume"`
+}
+
+type apiUserUpdateRequest struct{
+  IsAdmin bool  `json:"IsAdmin"`
+}
+
+var apiOperations = []apiOperation{
+  {Method: "GET", Path: "/openapi.json", Summary: "This document",
+    Response: map[string]interface{}{}},
+  {Method: "GET", Path: "/me", Summary: "The signed in user",
+    Scope: models.ScopeRead, Auth: true, Response: apiUser{}},
+  {Method: "GET", Path: "/rooms", Summary: "Rooms visible to the caller",
+    Scope: models.ScopeRead, Response: apiRoomList{}},
+  {Method: "POST", Path: "/rooms", Summary: "Create a room",
+    Scope: models.ScopeQueue, Auth: true, Request: apiRoomCreateRequest{}, Response: apiRoom{}},
+  {Method: "GET", Path: "/rooms/:roomId", Summary: "A room with its queue and members",
+    Scope: models.ScopeRead, Query: []string{"invite"}, Response: apiRoomDetail{}},
+  {Method: "POST", Path: "/rooms/:roomId/join", Summary: "Join a room, leaving the current one",
+    Scope: models.ScopeQueue, Auth: true, Request: apiJoinRequest{}, Response: apiRoomDetail{}},
+  {Method: "POST", Path: "/rooms/:roomId/leave", Summary: "Leave a room",
+    Scope: models.ScopeQueue, Auth: true, Response: apiRoom{}},
+  {Method: "POST", Path: "/rooms/:roomId/skip", Summary: "Vote to skip the current track",
+    Scope: models.ScopeQueue, Auth: true, Response: apiSkipResult{}},
+  {Method: "GET", Path: "/rooms/:roomId/queue", Summary: "What's playing and the queue in play order",
+    Scope: models.ScopeRead, Query: []string{"invite"}, Response: apiQueue{}},
+  {Method: "POST", Path: "/rooms/:roomId/queue", Summary: "Queue a track",
+    Scope: models.ScopeQueue, Auth: true, Request: apiQueueAddRequest{}, Response: models.EntryData{}},
+  {Method: "POST", Path: "/rooms/:roomId/queue/:entryId/remove", Summary: "Remove a queued track",
+    Scope: models.ScopeQueue, Auth: true, Response: apiQueue{}},
+  {Method: "POST", Path: "/rooms/:roomId/queue/:entryId/move", Summary: "Move a queued track up or down",
+    Scope: models.ScopeQueue, Auth: true, Request: apiDirectionRequest{}, Response: apiQueue{}},
+  {Method: "POST", Path: "/rooms/:roomId/queue/:entryId/vote", Summary: "Vote a queued track up or down, voting the same way again clears the vote",
+    Scope: models.ScopeQueue, Auth: true, Request: apiDirectionRequest{}, Response: apiVoteResult{}},
+  {Method: "GET", Path: "/rooms/:roomId/player", Summary: "Playback status",
+    Scope: models.ScopeRead, Auth: true, Response: apiPlayerStatus{}},
+  {Method: "POST", Path: "/rooms/:roomId/player/control", Summary: "Pause, resume or change the volume",
+    Scope: models.ScopePlayer, Auth: true, Request: apiPlayerControlRequest{}, Response: apiPlayerStatus{}},
+  {Method: "GET", Path: "/users", Summary: "All users, admins only",
+    Scope: models.ScopeAdmin, Auth: true, Response: apiUserList{}},
+  {Method: "GET", Path: "/users/:userId", Summary: "A user",
+    Scope: models.ScopeRead, Auth: true, Response: apiUser{}},
+  {Method: "POST", Path: "/users/:userId", Summary: "Update a user, only admins can change IsAdmin",
+    Scope: models.ScopeAdmin, Auth: true, Request: apiUserUpdateRequest{}, Response: apiUser{}},
+}
+
+func CheckApiSpec(routes []ApiRoute) error{
+  /*
+    Both ways round, a route without an operation is undocumented and an
+    operation without a route documents something that isn't there
+  */
+  documented := map[ApiRoute]bool{}
+  for _, op := range apiOperations{
+    documented[ApiRoute{Method: op.Method, Path: op.Path}] = true
+  }
+
+  var problems []string
+  for _, r := range routes{
+    if !documented[r]{
+      problems = append(problems, fmt.Sprintf("%s %s has no OpenAPI operation", r.Method, r.Path))
+    }
+    delete(documented, r)
+  }
+  for r := range documented{
+    problems = append(problems, fmt.Sprintf("%s %s is documented but not routed", r.Method, r.Path))
+  }
+
+  if len(problems) > 0{
+    sort.Strings(problems)
+    return fmt.Errorf("API spec out of date: %s", strings.Join(problems, "; "))
+  }
+  return nil
+}
+
+func ApiSpec(c *gin.Context){
+  c.JSON(200, apiSpec())
+}
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+func apiSpec() map[string]interface{}{
+  schemas := apiSchemas{}
+  errorResponse := map[string]interface{}{
+    "description": "Error",
+    "content": jsonContent(schemas.of(reflect.TypeOf(apiError{}))),
+  }
+
+  paths := map[string]interface{}{}
+  for _, op := range apiOperations{
+    path := pathParam.ReplaceAllString(op.Path, "{$1}")
+    item, ok := paths[path].(map[string]interface{})
+    if !ok{
+      item = map[string]interface{}{}
+      paths[path] = item
+    }
+
+    var params []interface{}
+    for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1){
+      params = append(params, map[string]interface{}{
+        "name": m[1],
+        "in": "path",
+        "required": true,
+        "schema": map[string]interface{}{"type": "integer"},
+      })
+    }
+    for _, q := range op.Query{
+      params = append(params, map[string]interface{}{
+        "name": q,
+        "in": "query",
+        "schema": map[string]interface{}{"type": "string"},
+      })
+    }
+
+    operation := map[string]interface{}{
+      "summary": op.Summary,
+      "responses": map[string]interface{}{
+        "200": map[string]interface{}{
+          "description": "OK",
+          "content": jsonContent(schemas.of(reflect.TypeOf(op.Response))),
+        },
+        "default": errorResponse,
+      },
+    }
+    if len(params) > 0{
+      operation["parameters"] = params
+    }
+    if op.Request != nil{
+      body := schemas.of(reflect.TypeOf(op.Request))
+      operation["requestBody"] = map[string]interface{}{
+        "content": map[string]interface{}{
+          "application/json": map[string]interface{}{"schema": body},
+          "application/x-www-form-urlencoded": map[string]interface{}{"schema": body},
+        },
+      }
+    }
+    if len(op.Scope) > 0{
+      security := []interface{}{
+        map[string]interface{}{"token": []string{}},
+        map[string]interface{}{"session": []string{}},
+      }
+      if !op.Auth{
+        // Anyone can call it, signing in just changes what's visible
+        security = append(security, map[string]interface{}{})
+      }
+      operation["security"] = security
+      operation["x-token-scope"] = op.Scope
+      operation["description"] = fmt.Sprintf("Tokens need the %s scope: %s", op.Scope, models.ScopeDescriptions[op.Scope])
+    }
+
+    item[strings.ToLower(op.Method)] = operation
+  }
+
+  return map[string]interface{}{
+    "openapi": "3.0.3",
+    "info": map[string]interface{}{
+      "title": "Jukebox",
+      "version": "1",
+    },
+    "servers": []interface{}{
+      map[string]interface{}{"url": helpers.ApiPrefix},
+    },
+    "paths": paths,
+    "components": map[string]interface{}{
+      "schemas": schemas,
+      "securitySchemes": map[string]interface{}{
+        "token": map[string]interface{}{
+          "type": "http",
+          "scheme": "bearer",
+          "description": "A personal API token from your user page",
+        },
+        "session": map[string]interface{}{
+          "type": "apiKey",
+          "in": "cookie",
+          "name": "jukebox_user",
+        },
+      },
+    },
+  }
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{}{
+  return map[string]interface{}{
+    "application/json": map[string]interface{}{"schema": schema},
+  }
+}
+
+// Named structs end up in components, keyed by the type name without "api"
+type apiSchemas map[string]interface{}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (s apiSchemas) of(t reflect.Type) map[string]interface{}{
+  switch{
+  case t == timeType:
+    return map[string]interface{}{"type": "string", "format": "date-time"}
+  case t.Kind() == reflect.Ptr:
+    return map[string]interface{}{
+      "allOf": []interface{}{s.of(t.Elem())},
+      "nullable": true,
+    }
+  case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+    return map[string]interface{}{"type": "array", "items": s.of(t.Elem())}
+  case t.Kind() == reflect.Map:
+    return map[string]interface{}{"type": "object"}
+  case t.Kind() == reflect.Struct:
+    name := strings.Title(strings.TrimPrefix(t.Name(), "api"))
+    if _, done := s[name]; !done{
+      // Claim the name first in case the struct refers to itself
+      s[name] = nil
+      s[name] = s.object(t)
+    }
+    return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+  case t.Kind() == reflect.Bool:
+    return map[string]interface{}{"type": "boolean"}
+  case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+    return map[string]interface{}{"type": "integer"}
+  case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+    return map[string]interface{}{"type": "number"}
+  }
+  return map[string]interface{}{"type": "string"}
+}
+
+func (s apiSchemas) object(t reflect.Type) map[string]interface{}{
+  properties := map[string]interface{}{}
+  var required []string
+  s.fields(t, properties, &required)
+
+  obj := map[string]interface{}{
+    "type": "object",
+    "properties": properties,
+  }
+  if len(required) > 0{
+    obj["required"] = required
+  }
+  return obj
+}
+
+func (s apiSchemas) fields(t reflect.Type, properties map[string]interface{}, required *[]string){
+  for i := 0; i < t.NumField(); i++{
+    f := t.Field(i)
+    // Embedded structs are flattened by encoding/json, so here too
+    if f.Anonymous && f.Type.Kind() == reflect.Struct{
+      s.fields(f.Type, properties, required)
+      continue
+    }
+    if len(f.PkgPath) > 0{
+      continue
+    }
+
+    name := strings.Split(f.Tag.Get("json"), ",")[0]
+    if name == "-"{
+      continue
+    }
+    if len(name) == 0{
+      name = f.Name
+    }
+
+    prop := s.of(f.Type)
+    if enum := f.Tag.Get("enum"); len(enum) > 0{
+      prop["enum"] = strings.Split(enum, ",")
+    }
+    if doc := f.Tag.Get("doc"); len(doc) > 0{
+      prop["description"] = doc
+    }
+    properties[name] = prop
+
+    if f.Tag.Get("binding") == "required"{
+      *required = append(*required, name)
+    }
+  }
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/player"
+  "github.com/samarudge/jukebox/db"
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+func loadOwnedRoom(c *gin.Context) (models.Room, bool){
+  room, found := loadRoom(c)
+  if !found{
+    return room, false
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.IsOwner(u){
+    helpers.Send403(c, "Only the room's owners can change playback settings")
+    return room, false
+  }
+  return room, true
+}
+
+func PlayerView(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.CanModerate(u){
+    helpers.Send403(c, "Only the room's owners and DJs can control playback")
+    return
+  }
+
+  obj := gin.H{
+    "room": room,
+    "running": player.Running(room),
+    "isOwner": room.IsOwner(u),
+  }
+
+  s := models.Spotify{}
+  s.LoadSystem()
+  if s.Exists(){
+    devices, err := s.Client().Devices()
+    if err != nil && room.UsesSpotify(){
+      helpers.Send500(c, fmt.Sprintf("%s (%s)", "Could not list Spotify devices", err))
+      return
+    }
+
+    var deviceRows []gin.H
+    for _,device := range devices{
+      deviceRows = append(deviceRows, gin.H{
+        "device": device,
+        "selected": device.Id == room.DeviceID,
+      })
+    }
+    obj["devices"] = deviceRows
+  }
+
+  if room.PlayerConfigured(){
+    backend, err := player.NewBackend(room)
+    if err == nil{
+      status, err := backend.Status()
+      if err != nil{
+        obj["statusError"] = err.Error()
+      } else {
+        obj["status"] = status
+      }
+    }
+  }
+
+  helpers.Render(c, "rooms/player.html", obj)
+}
+
+func PlayerUpdate(c *gin.Context){
+  room, found := loadOwnedRoom(c)
+  if !found{
+    return
+  }
+
+  switch c.PostForm("backend"){
+  case models.BackendSpotify:
+    room.Backend = models.BackendSpotify
+  case models.BackendMpd:
+    room.Backend = models.BackendMpd
+  case models.BackendStream:
+    room.Backend = models.BackendStream
+  default:
+    helpers.Send400(c, "Unknown playback backend")
+    return
+  }
+  room.DeviceID = c.PostForm("deviceId")
+
+  // The server connects to whatever this is, so only admins get to pick it
+  mpdAddress := strings.TrimSpace(c.DefaultPostForm("mpdAddress", room.MpdAddress))
+  if mpdAddress != room.MpdAddress{
+    if !c.MustGet("authUser").(models.User).IsAdmin{
+      helpers.Send403(c, "Only admins can change the MPD address")
+      return
+    }
+    room.MpdAddress = mpdAddress
+  }
+
+  d := db.Db()
+  d.Save(&room)
+
+  if room.PlayerConfigured(){
+    player.Start(room)
+  } else {
+    player.Stop(room)
+  }
+  c.Redirect(302, room.Url() + "/player")
+}
+
+func PlayerControl(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.CanModerate(u){
+    helpers.Send403(c, "Only the room's owners and DJs can control playback")
+    return
+  }
+
+  if !room.PlayerConfigured(){
+    helpers.Send400(c, "No playback backend is configured for this room")
+    return
+  }
+
+  backend, err := player.NewBackend(room)
+  if err != nil{
+    helpers.Send500(c, err.Error())
+    return
+  }
+
+  switch c.PostForm("action"){
+  case "pause":
+    err = backend.Pause()
+  case "resume":
+    err = backend.Resume()
+  case "volume":
+    volume, convErr := strconv.Atoi(c.PostForm("volume"))
+    if convErr != nil || volume < 0 || volume > 100{
+      helpers.Send400(c, "Volume must be between 0 and 100")
+      return
+    }
+    err = backend.SetVolume(volume)
+  default:
+    helpers.Send400(c, "Unknown player action")
+    return
+  }
+
+  if err != nil{
+    helpers.Send500(c, fmt.Sprintf("%s (%s)", "Player error", err))
+    return
+  }
+
+  if helpers.IsApi(c){
+    status, err := statusJson(room)
+    if err != nil{
+      helpers.Send500(c, fmt.Sprintf("%s (%s)", "Player error", err))
+      return
+    }
+    c.JSON(200, status)
+    return
+  }
+  c.Redirect(302, room.Url() + "/player")
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/spotify"
+  "math/rand"
+  "net/url"
+  "strconv"
+  "strings"
+  "fmt"
+)
+
+// Stands in for a playlist ID to mean the user's liked songs
+const likedPlaylist = "liked"
+
+const playlistPageSize = 50
+
+// Stop reading huge playlists after this many tracks
+const maxPlaylistTracks = 1000
+
+func userSpotifyClient(c *gin.Context) (*spotify.Client, bool){
+  u := c.MustGet("authUser").(models.User)
+  if !u.HasSpotify(){
+    helpers.Send403(c, "Link your Spotify account to use your playlists")
+    return nil, false
+  }
+  return u.GetSpotify().Client(), true
+}
+
+func loadPlaylistId(c *gin.Context) (string, bool){
+  playlistId := c.Param("playlistId")
+  if !spotify.ValidId(playlistId){
+    helpers.Send404(c, "Playlist not found")
+    return playlistId, false
+  }
+  return playlistId, true
+}
+
+func playlistPage(client *spotify.Client, playlistId string, offset int) (spotify.PlaylistTracks, error){
+  if playlistId == likedPlaylist{
+    return client.SavedTracks(playlistPageSize, offset)
+  }
+  return client.PlaylistTracks(playlistId, playlistPageSize, offset)
+}
+
+func playlistPageLink(path string, page int) string{
+  u := url.URL{}
+  u.Path = path
+  q := u.Query()
+  q.Set("page", strconv.Itoa(page))
+  u.RawQuery = q.Encode()
+  return u.String()
+}
+
+func pageParam(c *gin.Context) int{
+  page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+  if err != nil || page < 1{
+    return 1
+  }
+  return page
+}
+
+func PlaylistList(c *gin.Context){
+  client, found := userSpotifyClient(c)
+  if !found{
+    return
+  }
+
+  page := pageParam(c)
+  playlists, err := client.MyPlaylists(playlistPageSize, (page-1)*playlistPageSize)
+  if err != nil{
+    helpers.Send500(c, fmt.Sprintf("%s (%s)", "Error loading your playlists", err))
+    return
+  }
+
+  obj := gin.H{
+    "playlists": playlists.Items,
+    "page": page,
+  }
+  if page > 1{
+    obj["prevLink"] = playlistPageLink("/music/playlists", page-1)
+  }
+  if playlists.HasNext(){
+    obj["nextLink"] = playlistPageLink("/music/playlists", page+1)
+  }
+  helpers.Render(c, "music/playlists.html", obj)
+}
+
+func PlaylistView(c *gin.Context){
+  client, found := userSpotifyClient(c)
+  if !found{
+    return
+  }
+
+  playlistId, found := loadPlaylistId(c)
+  if !found{
+    return
+  }
+
+  name := "Liked Songs"
+  if playlistId != likedPlaylist{
+    playlist, err := client.Playlist(playlistId)
+    if err != nil{
+      helpers.Send404(c, "Playlist not found")
+      return
+    }
+    name = playlist.Name
+  }
+
+  page := pageParam(c)
+  tracks, err := playlistPage(client, playlistId, (page-1)*playlistPageSize)
+  if err != nil{
+    helpers.Send500(c, fmt.Sprintf("%s (%s)", "Error loading playlist tracks", err))
+    return
+  }
+
+  path := fmt.Sprintf("/music/playlists/%s", playlistId)
+  obj := gin.H{
+    "name": name,
+    "tracks": tracks.Items,
+    "total": tracks.Total,
+    "page": page,
+  }
+  if room, inRoom := c.Get("currentRoom"); inRoom{
+    obj["queueUrl"] = path + "/queue"
+    obj["roomName"] = room.(models.Room).Name
+  }
+  if page > 1{
+    obj["prevLink"] = playlistPageLink(path, page-1)
+  }
+  if tracks.HasNext(){
+    obj["nextLink"] = playlistPageLink(path, page+1)
+  }
+  helpers.Render(c, "music/playlist.html", obj)
+}
+
+type rejectedTrack struct{
+  Title   string
+  Artist  string
+  Reason  string
+}
+
+func PlaylistQueue(c *gin.Context){
+  client, found := userSpotifyClient(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  room, inRoom := u.CurrentRoom()
+  if !inRoom || !room.HasMember(u){
+    helpers.Send400(c, "Join a room before queueing tracks")
+    return
+  }
+
+  c.Request.ParseForm()
+  selected := make(map[string]bool)
+  for _, uri := range c.Request.PostForm["trackUri"]{
+    selected[uri] = true
+  }
+  shuffle, _ := strconv.Atoi(c.PostForm("shuffle"))
+  if shuffle < 1 && len(selected) == 0{
+    helpers.Send400(c, "Pick some tracks to queue")
+    return
+  }
+
+  // Details come from Spotify rather than the form so room policies can be trusted
+  playlistId, found := loadPlaylistId(c)
+  if !found{
+    return
+  }
+
+  var entries []models.QueueEntry
+  for offset := 0; offset < maxPlaylistTracks; offset += playlistPageSize{
+    tracks, err := playlistPage(client, playlistId, offset)
+    if err != nil{
+      helpers.Send500(c, fmt.Sprintf("%s (%s)", "Error loading playlist tracks", err))
+      return
+    }
+
+    for _, item := range tracks.Items{
+      t := item.Track
+      if !strings.HasPrefix(t.Uri, "spotify:track:"){
+        // Local files and podcast episodes can't be queued
+        continue
+      }
+      if shuffle < 1 && !selected[t.Uri]{
+        continue
+      }
+      entries = append(entries, models.QueueEntry{
+        TrackUri: t.Uri,
+        Title: t.Name,
+        Artist: t.ArtistNames(),
+        DurationMs: t.DurationMs,
+        Explicit: t.Explicit,
+      })
+    }
+
+    if !tracks.HasNext(){
+      break
+    }
+  }
+
+  if shuffle > 0{
+    for i := range entries{
+      j := rand.Intn(i+1)
+      entries[i], entries[j] = entries[j], entries[i]
+    }
+  }
+
+  queued := 0
+  var rejected []rejectedTrack
+  for _, e := range entries{
+    if shuffle > 0 && queued == shuffle{
+      break
+    }
+
+    if err := room.CheckPolicy(u, e); err != nil{
+      rejected = append(rejected, rejectedTrack{
+        Title: e.Title,
+        Artist: e.Artist,
+        Reason: err.Error(),
+      })
+      continue
+    }
+    room.Enqueue(u, &e)
+    queued++
+  }
+
+  if len(rejected) == 0{
+    c.Redirect(302, room.Url())
+    return
+  }
+
+  helpers.Render(c, "music/queued.html", gin.H{
+    "room": room,
+    "queued": queued,
+    "rejected": rejected,
+  })
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/spotify"
+  "fmt"
+)
+
+func loadQueueEntry(c *gin.Context, room models.Room) (models.QueueEntry, bool){
+  d := db.Db()
+  e := models.QueueEntry{}
+  e.ById(c.Param("entryId"))
+
+  // Played, skipped and playing entries are no longer in the queue
+  if d.NewRecord(e) || e.RoomID != uint64(room.ID) || e.Status != models.QueueStatusQueued{
+    helpers.Send404(c, "Queue entry not found")
+    return e, false
+  }
+  return e, true
+}
+
+type queueRow struct{
+  models.QueueEntry
+  Index     int
+  Removable bool
+  Movable   bool
+  Votable   bool
+  UpVoted   bool
+  DownVoted bool
+}
+
+func queueRows(c *gin.Context, room models.Room, entries []models.QueueEntry) []queueRow{
+  u := models.User{}
+  userInterface, _ := c.Get("authUser")
+  if userInterface != nil{
+    u = userInterface.(models.User)
+  }
+
+  var rows []queueRow
+  for i, e := range entries{
+    row := queueRow{
+      QueueEntry: e,
+      Index: i + 1,
+    }
+    if u.ID != 0{
+      vote := e.UserVote(u)
+      row.Removable = e.CanRemove(u, room)
+      // Round robin decides the order itself
+      row.Movable = room.CanModerate(u) && !room.RoundRobin()
+      row.Votable = room.HasMember(u)
+      row.UpVoted = vote > 0
+      row.DownVoted = vote < 0
+    }
+    rows = append(rows, row)
+  }
+  return rows
+}
+
+func QueueView(c *gin.Context){
+  // The queue lives on the room page
+  c.Redirect(302, fmt.Sprintf("/rooms/%s#queue", c.Param("roomId")))
+}
+
+func lookupTrack(e *models.QueueEntry) error{
+  /*
+    Fills in the track's details from the library or Spotify, never from the
+    form, the room's policies depend on them
+  */
+  if len(e.TrackUri) == 0{
+    return fmt.Errorf("No track was given to queue")
+  }
+
+  if e.IsLocal(){
+    t := models.LibraryTrack{}
+    if !t.ByUri(e.TrackUri){
+      return fmt.Errorf("Track not found in the library")
+    }
+    e.Title = t.Title
+    e.Artist = t.Artist
+    e.DurationMs = t.DurationMs
+    e.Explicit = false
+    return nil
+  }
+
+  trackId, ok := spotify.TrackId(e.TrackUri)
+  if !ok{
+    return fmt.Errorf("Only library and Spotify tracks can be queued")
+  }
+
+  s := models.Spotify{}
+  s.LoadSystem()
+  if !s.Exists(){
+    return fmt.Errorf("The system Spotify account has not been configured")
+  }
+  t, err := s.Client().Track(trackId)
+  if err != nil{
+    return fmt.Errorf("%s (%s)", "Could not find that track on Spotify", err)
+  }
+  e.Title = t.Name
+  e.Artist = t.ArtistNames()
+  e.DurationMs = t.DurationMs
+  e.Explicit = t.Explicit
+  return nil
+}
+
+func QueueAdd(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.HasMember(u){
+    helpers.Send403(c, "You must join this room before queueing tracks")
+    return
+  }
+
+  e := models.QueueEntry{
+    TrackUri: c.PostForm("trackUri"),
+  }
+
+  if err := lookupTrack(&e); err != nil{
+    helpers.Send400(c, err.Error())
+    return
+  }
+
+  if err := room.CheckPolicy(u, e); err != nil{
+    helpers.Send400(c, err.Error())
+    return
+  }
+
+  room.Enqueue(u, &e)
+  helpers.Respond(c, room.Url(), e.EventData())
+}
+
+func QueueRemove(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  e, found := loadQueueEntry(c, room)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !e.CanRemove(u, room){
+    helpers.Send403(c, "Only the person who queued this track or the room's owners and DJs can remove it")
+    return
+  }
+
+  e.Remove()
+  helpers.Respond(c, room.Url(), queueJson(room))
+}
+
+func QueueMove(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  e, found := loadQueueEntry(c, room)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.CanModerate(u){
+    helpers.Send403(c, "Only the room's owners and DJs can reorder the queue")
+    return
+  }
+
+  room.MoveEntry(e, c.PostForm("direction") == "up")
+  helpers.Respond(c, room.Url(), queueJson(room))
+}
+
+func QueueVote(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  e, found := loadQueueEntry(c, room)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.HasMember(u){
+    helpers.Send403(c, "Only members of this room can vote")
+    return
+  }
+
+  value := 1
+  if c.PostForm("direction") == "down"{
+    value = -1
+  }
+
+  e.Vote(u, value)
+  helpers.Respond(c, room.Url(), apiVoteResult{
+    Entry: e.EventData(),
+    Vote: e.UserVote(u),
+  })
+}
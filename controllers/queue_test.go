@@ -0,0 +1,52 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/testdb"
+  "fmt"
+  "net/http/httptest"
+  "testing"
+)
+
+func queuePost(u models.User, handler gin.HandlerFunc, room models.Room, e models.QueueEntry, action string) *httptest.ResponseRecorder{
+  gin.SetMode(gin.TestMode)
+  router := gin.New()
+  router.POST("/rooms/:roomId/queue/:entryId/" + action, func(c *gin.Context){
+    c.Set("authUser", u)
+  }, handler)
+
+  req := httptest.NewRequest("POST", fmt.Sprintf("/rooms/%d/queue/%d/%s", room.ID, e.ID, action), nil)
+  w := httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  return w
+}
+
+func TestQueueOnlyQueuedEntries(t *testing.T){
+  defer testdb.Open(t)()
+
+  u := testdb.User(t, models.Oauth2{Provider: "google"})
+  room := testdb.Room(t, u, "Office", "spotify:track:first", "spotify:track:second")
+  room.JoinUser(u)
+  u.RoomID = uint64(room.ID)
+
+  playing, _ := room.Advance(false)
+  handlers := map[string]gin.HandlerFunc{
+    "remove": QueueRemove,
+    "move": QueueMove,
+    "vote": QueueVote,
+  }
+  for action, handler := range handlers{
+    if w := queuePost(u, handler, room, playing, action); w.Code != 404{
+      t.Errorf("%s on the playing entry returned %d, want 404", action, w.Code)
+    }
+  }
+  if current, _ := room.NowPlaying(); current.ID != playing.ID{
+    t.Error("The playing entry was removed from the queue")
+  }
+
+  queued, _ := room.NextEntry()
+  if w := queuePost(u, QueueRemove, room, queued, "remove"); w.Code != 302{
+    t.Errorf("Removing a queued entry returned %d, want 302", w.Code)
+  }
+}
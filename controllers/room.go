@@ -5,13 +5,23 @@ import(
   "github.com/samarudge/jukebox/helpers"
   "github.com/samarudge/jukebox/models"
   "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/config"
+  "github.com/samarudge/jukebox/spotify"
   "fmt"
+  "net/url"
+  "strconv"
+  "strings"
+  "time"
 )
 
 func RoomCreate(c *gin.Context){
   roomName := c.PostForm("roomName")
 
   if len(roomName) == 0{
+    if helpers.IsApi(c){
+      helpers.Send400(c, "You must enter a room name")
+      return
+    }
     helpers.Render(c, "rooms/new.html", gin.H{"error":"You must enter a room name"})
     return
   }
@@ -19,7 +29,7 @@ func RoomCreate(c *gin.Context){
   r := models.Room{}
   user, _ := c.Get("authUser")
   r.Create(user.(models.User), roomName)
-  c.Redirect(302, "/rooms")
+  helpers.Respond(c, "/rooms", roomJson(r))
 }
 
 func loadRoom(c *gin.Context) (models.Room, bool){
@@ -41,21 +51,398 @@ func RoomJoin(c *gin.Context){
   }
 
   u := c.MustGet("authUser").(models.User)
+  if !checkInvite(c, room, u, c.PostForm("invite")){
+    return
+  }
+
+  // Make sure people know they are leaving their current room, API clients asked to join so just switch
+  if current, inRoom := u.CurrentRoom(); inRoom && current.ID != room.ID && !helpers.IsApi(c){
+    helpers.Render(c, "rooms/switch.html", gin.H{
+      "room": room,
+      "previousRoom": current,
+      "invite": c.PostForm("invite"),
+    })
+    return
+  }
+
+  joinRoom(c, room, u)
+}
+
+func checkInvite(c *gin.Context, room models.Room, u models.User, token string) bool{
+  if room.VisibleTo(u){
+    return true
+  }
+
+  err := helpers.VerifyInvite(token, room)
+  if err != nil{
+    helpers.Send403(c, err.Error())
+    return false
+  }
+  return true
+}
 
+func joinRoom(c *gin.Context, room models.Room, u models.User){
   err := room.JoinUser(u)
   if err != nil{
-    helpers.Send500(c, fmt.Sprintf("%s (%s)", "Error during authentication", err))
+    helpers.Send500(c, fmt.Sprintf("%s (%s)", "Error joining room", err))
   } else {
-    c.Redirect(302, "/")
+    helpers.Respond(c, "/", roomDetailJson(room))
+  }
+}
+
+func RoomSwitch(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !checkInvite(c, room, u, c.PostForm("invite")){
+    return
+  }
+
+  joinRoom(c, room, u)
+}
+
+func RoomLeave(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.HasMember(u){
+    helpers.Send400(c, "You are not a member of this room")
+    return
+  }
+
+  room.RemoveUser(u)
+  helpers.Respond(c, "/rooms", roomJson(room))
+}
+
+func RoomView(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := models.User{}
+  userInterface, _ := c.Get("authUser")
+  if userInterface != nil{
+    u = userInterface.(models.User)
+  }
+
+  invite := c.DefaultQuery("invite", "")
+  if !checkInvite(c, room, u, invite){
+    return
+  }
+
+  obj := gin.H{
+    "room": room,
+    "creator": room.CreatorUser(),
+    "members": memberRows(c, room),
+    "queue": queueRows(c, room, room.ProjectedQueue()),
+    "history": room.History(10),
+    "membershipHistory": room.MembershipHistory(10),
+    "skipVotesNeeded": room.SkipVotesNeeded(),
+    "invite": invite,
+    "streamUrl": room.StreamUrl(),
+  }
+  if invite != ""{
+    // People viewing a private room through an invite need it to listen too
+    obj["streamUrl"] = room.StreamUrl() + "?invite=" + url.QueryEscape(invite)
+  }
+  if nowPlaying, playing := room.NowPlaying(); playing{
+    obj["nowPlaying"] = nowPlaying
   }
+
+  if u.ID != 0{
+    obj["isMember"] = room.HasMember(u)
+    obj["canJoin"] = !room.HasMember(u)
+    obj["canSkip"] = room.HasMember(u) || room.CanModerate(u)
+    obj["canModerate"] = room.CanModerate(u)
+    obj["isOwner"] = room.IsOwner(u)
+    if room.IsOwner(u){
+      obj["webhooks"] = room.Webhooks()
+      obj["webhookEvents"] = webhookEventOptions()
+    }
+    obj["canExport"] = true
+    obj["socketUrl"] = room.Url() + "/socket"
+  }
+
+  helpers.Render(c, "rooms/view.html", obj)
 }
 
 func RoomList(c *gin.Context){
-  d := db.Db()
+  u := models.User{}
+  userInterface, _ := c.Get("authUser")
+  if userInterface != nil{
+    u = userInterface.(models.User)
+  }
 
-  var rooms []models.Room
-  d.Order("name").Find(&rooms)
   helpers.Render(c, "rooms/list.html", gin.H{
-    "rooms": rooms,
+    "rooms": models.VisibleRooms(u),
+  })
+}
+
+func RoomSkip(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.HasMember(u) && !room.CanModerate(u){
+    helpers.Send403(c, "Only members of this room can vote to skip")
+    return
+  }
+
+  skipped, err := skipVote(&room, u)
+  if err != nil{
+    helpers.Send400(c, err.Error())
+    return
+  }
+  helpers.Respond(c, room.Url(), apiSkipResult{
+    Skipped: skipped,
+    NowPlaying: room.NowPlayingData(),
+  })
+}
+
+func skipVote(room *models.Room, u models.User) (bool, error){
+  if room.CanModerate(u){
+    // Owners and DJs don't need to wait for a vote
+    return true, room.ForceSkip()
+  }
+  return room.VoteSkip(u)
+}
+
+func RoomSettings(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.IsOwner(u){
+    helpers.Send403(c, "Only the room's owners can change room settings")
+    return
+  }
+
+  skipPercent, err := strconv.Atoi(c.PostForm("skipPercent"))
+  if err != nil || skipPercent < 1 || skipPercent > 100{
+    helpers.Send400(c, "Skip threshold must be a percentage between 1 and 100")
+    return
+  }
+  room.SkipThreshold = float64(skipPercent)/100
+
+  maxQueued, valid := settingsLimit(c, "maxQueuedPerUser", "Tracks per user")
+  if !valid{
+    return
+  }
+  maxMinutes, valid := settingsLimit(c, "maxTrackMinutes", "Maximum track length")
+  if !valid{
+    return
+  }
+  repeatHours, valid := settingsLimit(c, "repeatWindowHours", "Repeat window")
+  if !valid{
+    return
+  }
+  room.MaxQueuedPerUser = maxQueued
+  room.MaxTrackSeconds = maxMinutes*60
+  room.RepeatWindowHours = repeatHours
+  room.BlockExplicit = c.PostForm("blockExplicit") == "true"
+
+  queueMode := c.DefaultPostForm("queueMode", models.QueueModeVotes)
+  if !models.ValidQueueMode(queueMode){
+    helpers.Send400(c, "Queue mode must be votes or round robin")
+    return
+  }
+  room.QueueMode = queueMode
+
+  autoDJ := c.PostForm("autoDJ")
+  if !models.ValidAutoDJ(autoDJ){
+    helpers.Send400(c, "Unknown auto-DJ source")
+    return
+  }
+  room.AutoDJ = autoDJ
+  room.AutoDJPlaylist = strings.TrimSpace(c.PostForm("autoDJPlaylist"))
+  if room.AutoDJFromPlaylist() && room.AutoDJPlaylist == ""{
+    helpers.Send400(c, "Give a Spotify playlist for the auto-DJ to play from")
+    return
+  }
+  if room.AutoDJPlaylist != "" && !spotify.ValidId(spotify.PlaylistId(room.AutoDJPlaylist)){
+    helpers.Send400(c, "The auto-DJ playlist should be a Spotify playlist link, URI or ID")
+    return
+  }
+
+  room.Private = c.PostForm("private") == "true"
+  if room.Private && room.InviteSecret == ""{
+    room.RegenerateInviteSecret()
+  }
+
+  d := db.Db()
+  d.Save(&room)
+  c.Redirect(302, room.Url())
+}
+
+func settingsLimit(c *gin.Context, field string, name string) (int, bool){
+  // Blank or zero turns the limit off
+  raw := c.PostForm(field)
+  if raw == ""{
+    return 0, true
+  }
+
+  value, err := strconv.Atoi(raw)
+  if err != nil || value < 0{
+    helpers.Send400(c, fmt.Sprintf("%s must be zero or a positive number", name))
+    return 0, false
+  }
+  return value, true
+}
+
+type memberRow struct{
+  models.User
+  Role          string
+  IsOwnerRole   bool
+  IsDJRole      bool
+  CanKick       bool
+  CanSetRole    bool
+}
+
+func memberRows(c *gin.Context, room models.Room) []memberRow{
+  u := models.User{}
+  userInterface, _ := c.Get("authUser")
+  if userInterface != nil{
+    u = userInterface.(models.User)
+  }
+  isOwner := room.IsOwner(u)
+
+  var rows []memberRow
+  for _,member := range room.MemberList(){
+    role := room.RoleOf(member)
+    rows = append(rows, memberRow{
+      User: member,
+      Role: role,
+      IsOwnerRole: role == models.RoleOwner,
+      IsDJRole: role == models.RoleDJ,
+      CanKick: room.CanKick(u, member),
+      CanSetRole: isOwner && member.ID != u.ID && uint64(member.ID) != room.CreatorID,
+    })
+  }
+  return rows
+}
+
+func loadMember(c *gin.Context, room models.Room) (models.User, bool){
+  member := models.User{}
+  member.ById(c.Param("userId"))
+
+  if member.ID == 0{
+    helpers.Send404(c, "User not found")
+    return member, false
+  }
+  return member, true
+}
+
+func RoomKick(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  member, found := loadMember(c, room)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.CanKick(u, member){
+    helpers.Send403(c, "You are not allowed to remove this member from the room")
+    return
+  }
+
+  room.Kick(member)
+  c.Redirect(302, room.Url())
+}
+
+func RoomSetRole(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  member, found := loadMember(c, room)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.IsOwner(u){
+    helpers.Send403(c, "Only the room's owners can change roles")
+    return
+  }
+  if uint64(member.ID) == room.CreatorID{
+    helpers.Send400(c, "The room's creator is always an owner")
+    return
+  }
+
+  role := c.PostForm("role")
+  if !models.ValidRole(role){
+    helpers.Send400(c, "Unknown role")
+    return
+  }
+
+  room.SetRole(member, role)
+  c.Redirect(302, room.Url())
+}
+
+const maxInviteHours = 24*30
+
+func RoomInvite(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.IsOwner(u){
+    helpers.Send403(c, "Only the room's owners can invite people")
+    return
+  }
+
+  hours, err := strconv.Atoi(c.DefaultPostForm("expiresHours", "24"))
+  if err != nil || hours < 1 || hours > maxInviteHours{
+    helpers.Send400(c, fmt.Sprintf("Invites must expire within 1 and %d hours", maxInviteHours))
+    return
+  }
+
+  if room.InviteSecret == ""{
+    room.RegenerateInviteSecret()
+  }
+
+  expires := time.Now().UTC().Add(time.Hour*time.Duration(hours))
+  link, _ := url.Parse(config.Config.Url)
+  invite, _ := url.Parse(helpers.InviteLink(room, expires))
+  link = link.ResolveReference(invite)
+
+  helpers.Render(c, "rooms/invite.html", gin.H{
+    "room": room,
+    "inviteLink": link.String(),
+    "expires": expires.Format("Mon Jan 2 2006 15:04 MST"),
   })
 }
+
+func RoomInviteRevoke(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.IsOwner(u){
+    helpers.Send403(c, "Only the room's owners can revoke invites")
+    return
+  }
+
+  room.RegenerateInviteSecret()
+  c.Redirect(302, room.Url())
+}
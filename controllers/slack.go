@@ -0,0 +1,320 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/config"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/library"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/slack"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "net/url"
+  "strconv"
+  "strings"
+  "time"
+)
+
+/*
+  The /jukebox slash command and its buttons. Slack users are matched to
+  jukebox users by the email they sign in to Google with
+*/
+
+const slackSearchResults = 5
+// Commands and button payloads are a few KB, this is well clear of them
+const slackMaxBody = 1 << 20
+const slackQueueAction = "queue"
+
+const slackHelp = "*/jukebox search <query>* find tracks to queue\n" +
+  "*/jukebox queue <query or URI>* queue the best match\n" +
+  "*/jukebox playing* what's playing in your room\n" +
+  "*/jukebox skip* vote to skip the current track\n" +
+  "*/jukebox join <room>* join a room by name or ID"
+
+func slackRequest(c *gin.Context) (url.Values, bool){
+  if !slack.Configured(){
+    helpers.Send404(c, "Slack is not configured")
+    return nil, false
+  }
+
+  // Read before the signature is checked, so anyone can send it
+  c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, slackMaxBody)
+  body, err := ioutil.ReadAll(c.Request.Body)
+  if err != nil{
+    helpers.Send400(c, fmt.Sprintf("%s (%s)", "Could not read request", err))
+    return nil, false
+  }
+
+  err = slack.Verify(
+    slack.SigningSecret,
+    c.Request.Header.Get("X-Slack-Request-Timestamp"),
+    body,
+    c.Request.Header.Get("X-Slack-Signature"),
+    time.Now(),
+  )
+  if err != nil{
+    log.WithFields(log.Fields{
+      "error": err,
+      "remote": c.ClientIP(),
+    }).Warning("Rejected Slack request")
+    helpers.Send403(c, err.Error())
+    return nil, false
+  }
+
+  form, err := url.ParseQuery(string(body))
+  if err != nil{
+    helpers.Send400(c, fmt.Sprintf("%s (%s)", "Could not decode request", err))
+    return nil, false
+  }
+  return form, true
+}
+
+func slackUser(slackId string) (models.User, error){
+  u := models.User{}
+  email, err := slack.NewClient().UserEmail(slackId)
+  if err != nil{
+    return u, fmt.Errorf("Could not find your email address on Slack (%s)", err)
+  }
+  if !u.ByGoogleEmail(email){
+    return u, fmt.Errorf("Nobody signs in to the jukebox as %s, sign in with Google at %s first", email, config.Config.Url)
+  }
+  return u, nil
+}
+
+func SlackCommand(c *gin.Context){
+  form, ok := slackRequest(c)
+  if !ok{
+    return
+  }
+
+  cmd := slack.ParseCommand(form)
+  slackAnswer(c, cmd.UserId, cmd.ResponseUrl, false, func(u models.User) slack.Message{
+    return slackCommand(u, cmd.Text)
+  })
+}
+
+func SlackAction(c *gin.Context){
+  form, ok := slackRequest(c)
+  if !ok{
+    return
+  }
+
+  interaction, err := slack.ParseInteraction(form)
+  if err != nil{
+    helpers.Send400(c, err.Error())
+    return
+  }
+
+  slackAnswer(c, interaction.User.Id, interaction.ResponseUrl, true, func(u models.User) slack.Message{
+    return slackInteraction(u, interaction)
+  })
+}
+
+func slackAnswer(c *gin.Context, slackId string, responseUrl string, replace bool, answer func(models.User) slack.Message){
+  // Slack wants an answer within 3 seconds, the result goes to the response URL
+  c.String(200, "")
+  go func(){
+    var m slack.Message
+    u, err := slackUser(slackId)
+    if err != nil{
+      m = slack.Ephemeral(err.Error())
+    } else {
+      m = answer(u)
+    }
+    m.ReplaceOriginal = replace
+
+    if err := slack.Respond(responseUrl, m); err != nil{
+      log.WithFields(log.Fields{
+        "error": err,
+      }).Error("Could not answer Slack request")
+    }
+  }()
+}
+
+func slackCommand(u models.User, text string) slack.Message{
+  verb, arg := text, ""
+  if i := strings.Index(text, " "); i >= 0{
+    verb, arg = text[:i], strings.TrimSpace(text[i+1:])
+  }
+
+  switch strings.ToLower(verb){
+  case "search":
+    return slackSearch(arg)
+  case "queue", "add":
+    return slackQueue(u, arg)
+  case "playing", "np", "now":
+    return slackNowPlaying(u)
+  case "skip":
+    return slackSkip(u)
+  case "join":
+    return slackJoin(u, arg)
+  }
+  return slack.Ephemeral(slackHelp)
+}
+
+func slackInteraction(u models.User, interaction slack.Interaction) slack.Message{
+  for _, a := range interaction.Actions{
+    if a.ActionId == slackQueueAction{
+      return slackQueueEntry(u, models.QueueEntry{TrackUri: a.Value})
+    }
+  }
+  return slack.Ephemeral("Unknown action")
+}
+
+func slackTracks(query string, limit int) ([]models.QueueEntry, error){
+  // Spotify first, the library fills the rest
+  var entries []models.QueueEntry
+
+  s := models.Spotify{}
+  s.LoadSystem()
+  if s.Exists(){
+    results, err := s.Client().Search(query, []string{"track"}, limit, 0)
+    if err != nil{
+      return entries, fmt.Errorf("%s (%s)", "Error searching Spotify", err)
+    }
+    for _, t := range results.Tracks.Items{
+      entries = append(entries, models.QueueEntry{
+        TrackUri: t.Uri,
+        Title: t.Name,
+        Artist: t.ArtistNames(),
+        DurationMs: t.DurationMs,
+        Explicit: t.Explicit,
+      })
+    }
+  }
+
+  if library.Configured() && len(entries) < limit{
+    tracks, _ := models.SearchLibrary(query, limit - len(entries), 0)
+    for _, t := range tracks{
+      entries = append(entries, models.QueueEntry{
+        TrackUri: t.Uri(),
+        Title: t.Title,
+        Artist: t.Artist,
+        DurationMs: t.DurationMs,
+      })
+    }
+  }
+  return entries, nil
+}
+
+func slackTrackName(e models.QueueEntry) string{
+  return fmt.Sprintf("*%s* by %s (%s)", slack.Escape(e.Title), slack.Escape(e.Artist), e.Duration())
+}
+
+func slackSearch(query string) slack.Message{
+  if len(query) == 0{
+    return slack.Ephemeral("What should I search for? */jukebox search <query>*")
+  }
+
+  entries, err := slackTracks(query, slackSearchResults)
+  if err != nil{
+    return slack.Ephemeral(err.Error())
+  }
+  if len(entries) == 0{
+    return slack.Ephemeral(fmt.Sprintf("Nothing found for %s", slack.Escape(query)))
+  }
+
+  m := slack.Ephemeral(fmt.Sprintf("Results for %s", slack.Escape(query)))
+  m.Blocks = []slack.Block{
+    {Type: "section", Text: slack.Markdown(m.Text)},
+  }
+  for _, e := range entries{
+    m.Blocks = append(m.Blocks, slack.Block{
+      Type: "section",
+      Text: slack.Markdown(slackTrackName(e)),
+      Accessory: slack.Button("Queue", slackQueueAction, e.TrackUri),
+    })
+  }
+  return m
+}
+
+func slackQueue(u models.User, arg string) slack.Message{
+  if len(arg) == 0{
+    return slack.Ephemeral("What should I queue? */jukebox queue <query or URI>*")
+  }
+
+  e := models.QueueEntry{TrackUri: arg}
+  if !strings.HasPrefix(arg, "spotify:track:") && !e.IsLocal(){
+    entries, err := slackTracks(arg, 1)
+    if err != nil{
+      return slack.Ephemeral(err.Error())
+    }
+    if len(entries) == 0{
+      return slack.Ephemeral(fmt.Sprintf("Nothing found for %s", slack.Escape(arg)))
+    }
+    e = entries[0]
+  }
+  return slackQueueEntry(u, e)
+}
+
+func slackQueueEntry(u models.User, e models.QueueEntry) slack.Message{
+  room, inRoom := u.CurrentRoom()
+  if !inRoom{
+    return slack.Ephemeral("Join a room first with */jukebox join <room>*")
+  }
+
+  if err := lookupTrack(&e); err != nil{
+    return slack.Ephemeral(err.Error())
+  }
+  if err := room.CheckPolicy(u, e); err != nil{
+    return slack.Ephemeral(err.Error())
+  }
+
+  room.Enqueue(u, &e)
+  return slack.Ephemeral(fmt.Sprintf("Queued %s in %s", slackTrackName(e), slack.Escape(room.Name)))
+}
+
+func slackNowPlaying(u models.User) slack.Message{
+  room, inRoom := u.CurrentRoom()
+  if !inRoom{
+    return slack.Ephemeral("You're not in a room, join one with */jukebox join <room>*")
+  }
+
+  queued := len(room.ProjectedQueue())
+  current, playing := room.NowPlaying()
+  if !playing{
+    return slack.Ephemeral(fmt.Sprintf("Nothing is playing in %s, %d tracks queued", slack.Escape(room.Name), queued))
+  }
+  return slack.Ephemeral(fmt.Sprintf("Now playing in %s: %s, queued by %s. %d tracks queued",
+    slack.Escape(room.Name), slackTrackName(current), slack.Escape(current.AddedByName()), queued))
+}
+
+func slackSkip(u models.User) slack.Message{
+  room, inRoom := u.CurrentRoom()
+  if !inRoom{
+    return slack.Ephemeral("You're not in a room, join one with */jukebox join <room>*")
+  }
+
+  skipped, err := skipVote(&room, u)
+  if err != nil{
+    return slack.Ephemeral(err.Error())
+  }
+  if !skipped{
+    return slack.Ephemeral("Your vote to skip was counted, more are needed")
+  }
+  return slack.Ephemeral("Skipped")
+}
+
+func slackJoin(u models.User, arg string) slack.Message{
+  if len(arg) == 0{
+    return slack.Ephemeral("Which room? */jukebox join <room>*")
+  }
+
+  // Only rooms the user could see on the site, invites need the link
+  id, _ := strconv.ParseUint(arg, 10, 64)
+  for _, room := range models.VisibleRooms(u){
+    if uint64(room.ID) != id && !strings.EqualFold(room.Name, arg){
+      continue
+    }
+    if room.HasMember(u){
+      return slack.Ephemeral(fmt.Sprintf("You're already in %s", slack.Escape(room.Name)))
+    }
+    if err := room.JoinUser(u); err != nil{
+      return slack.Ephemeral(fmt.Sprintf("%s (%s)", "Error joining room", err))
+    }
+    return slack.Ephemeral(fmt.Sprintf("Joined %s", slack.Escape(room.Name)))
+  }
+  return slack.Ephemeral(fmt.Sprintf("No room called %s", slack.Escape(arg)))
+}
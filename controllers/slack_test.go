@@ -0,0 +1,210 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/slack"
+  "github.com/samarudge/jukebox/testdb"
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+)
+
+/*
+  Recorded requests from Slack are replayed against the handlers, with
+  Slack's API and response URLs pointed at a local fake
+*/
+
+const slackTestSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+const slackTestEmail = "roadrunner@example.com"
+
+type fakeSlack struct{
+  server    *httptest.Server
+  responses chan slack.Message
+  client    *http.Client
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack{
+  f := &fakeSlack{responses: make(chan slack.Message, 10), client: slack.HttpClient}
+  f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+    if r.URL.Path == "/api/users.info"{
+      if r.Header.Get("Authorization") != "Bearer xoxb-test"{
+        fmt.Fprint(w, `{"ok": false, "error": "invalid_auth"}`)
+        return
+      }
+      fmt.Fprintf(w, `{"ok": true, "user": {"id": %q, "profile": {"email": %q}}}`, r.URL.Query().Get("user"), slackTestEmail)
+      return
+    }
+
+    // Anything else is a response URL
+    m := slack.Message{}
+    if err := json.NewDecoder(r.Body).Decode(&m); err != nil{
+      t.Errorf("Could not decode response to %s: %s", r.URL.Path, err)
+    }
+    f.responses <- m
+  }))
+
+  slack.SigningSecret = slackTestSecret
+  slack.BotToken = "xoxb-test"
+  slack.HttpClient = &http.Client{Transport: fakeSlackTransport{f.server.Listener.Addr().String()}}
+  return f
+}
+
+func (f *fakeSlack) Close(){
+  f.server.Close()
+  slack.SigningSecret = ""
+  slack.BotToken = ""
+  slack.HttpClient = f.client
+}
+
+func (f *fakeSlack) response(t *testing.T) slack.Message{
+  select{
+  case m := <-f.responses:
+    return m
+  case <-time.After(5*time.Second):
+    t.Fatal("Nothing was sent to the response URL")
+  }
+  return slack.Message{}
+}
+
+// Sends every request to the fake, whatever host it was meant for
+type fakeSlackTransport struct{
+  host  string
+}
+
+func (t fakeSlackTransport) RoundTrip(r *http.Request) (*http.Response, error){
+  r.URL.Scheme = "http"
+  r.URL.Host = t.host
+  return http.DefaultTransport.RoundTrip(r)
+}
+
+func slackTestUser(t *testing.T) models.User{
+  a := models.Oauth2{Provider: "google"}
+  a.Username = slackTestEmail
+  a.Name = "Road Runner"
+  return testdb.User(t, a)
+}
+
+func slackReplay(t *testing.T, handler gin.HandlerFunc, fixture string, secret string) *httptest.ResponseRecorder{
+  body, err := ioutil.ReadFile(filepath.Join("testdata", fixture))
+  if err != nil{
+    t.Fatal(err)
+  }
+  return slackPost(handler, body, secret)
+}
+
+func slackPost(handler gin.HandlerFunc, body []byte, secret string) *httptest.ResponseRecorder{
+  timestamp := fmt.Sprintf("%d", time.Now().Unix())
+
+  req := httptest.NewRequest("POST", "/slack", bytes.NewReader(body))
+  req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+  req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+  req.Header.Set("X-Slack-Signature", slack.Signature(secret, timestamp, body))
+
+  gin.SetMode(gin.TestMode)
+  router := gin.New()
+  router.POST("/slack", handler)
+  w := httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  return w
+}
+
+func TestSlackCommandBadSignature(t *testing.T){
+  f := newFakeSlack(t)
+  defer f.Close()
+
+  w := slackReplay(t, SlackCommand, "slack_command.txt", "not the secret")
+  if w.Code != 403{
+    t.Errorf("Status = %d, want 403", w.Code)
+  }
+  select{
+  case m := <-f.responses:
+    t.Errorf("Answered an unsigned request with %q", m.Text)
+  case <-time.After(100*time.Millisecond):
+  }
+}
+
+func TestSlackCommandTooLarge(t *testing.T){
+  f := newFakeSlack(t)
+  defer f.Close()
+
+  body := append([]byte("text="), bytes.Repeat([]byte("a"), slackMaxBody)...)
+  w := slackPost(SlackCommand, body, slackTestSecret)
+  if w.Code != 400{
+    t.Errorf("Status = %d, want 400", w.Code)
+  }
+}
+
+func TestSlackCommandUnknownUser(t *testing.T){
+  defer testdb.Open(t)()
+  f := newFakeSlack(t)
+  defer f.Close()
+
+  w := slackReplay(t, SlackCommand, "slack_command.txt", slackTestSecret)
+  if w.Code != 200{
+    t.Fatalf("Status = %d, want 200", w.Code)
+  }
+  m := f.response(t)
+  if !strings.Contains(m.Text, "Nobody signs in to the jukebox as " + slackTestEmail){
+    t.Errorf("Unexpected answer %q", m.Text)
+  }
+  if m.ResponseType != slack.ResponseEphemeral{
+    t.Errorf("Response type = %q, want %q", m.ResponseType, slack.ResponseEphemeral)
+  }
+}
+
+func TestSlackCommandJoin(t *testing.T){
+  defer testdb.Open(t)()
+  f := newFakeSlack(t)
+  defer f.Close()
+
+  u := slackTestUser(t)
+  room := testdb.Room(t, u, "Office")
+
+  w := slackReplay(t, SlackCommand, "slack_command.txt", slackTestSecret)
+  if w.Code != 200{
+    t.Fatalf("Status = %d, want 200", w.Code)
+  }
+  if w.Body.Len() != 0{
+    t.Errorf("Answered the request itself with %q, should use the response URL", w.Body.String())
+  }
+  m := f.response(t)
+  if m.Text != "Joined Office"{
+    t.Errorf("Unexpected answer %q", m.Text)
+  }
+  if m.ReplaceOriginal{
+    t.Error("Command answers shouldn't replace the original message")
+  }
+
+  u.ById(fmt.Sprintf("%d", u.ID))
+  if current, inRoom := u.CurrentRoom(); !inRoom || current.ID != room.ID{
+    t.Error("User did not join the room")
+  }
+}
+
+func TestSlackActionOutsideRoom(t *testing.T){
+  defer testdb.Open(t)()
+  f := newFakeSlack(t)
+  defer f.Close()
+
+  slackTestUser(t)
+
+  w := slackReplay(t, SlackAction, "slack_action.txt", slackTestSecret)
+  if w.Code != 200{
+    t.Fatalf("Status = %d, want 200", w.Code)
+  }
+  m := f.response(t)
+  if !strings.HasPrefix(m.Text, "Join a room first"){
+    t.Errorf("Unexpected answer %q", m.Text)
+  }
+  if !m.ReplaceOriginal{
+    t.Error("Button answers should replace the original message")
+  }
+}
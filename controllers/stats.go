@@ -0,0 +1,42 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+)
+
+func RoomStats(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := models.User{}
+  userInterface, _ := c.Get("authUser")
+  if userInterface != nil{
+    u = userInterface.(models.User)
+  }
+  if !room.VisibleTo(u){
+    helpers.Send403(c, "This room is private")
+    return
+  }
+
+  helpers.Render(c, "stats.html", gin.H{
+    "title": room.Name,
+    "backLink": room.Url(),
+    "stats": models.RoomStats(room),
+    "perRoom": true,
+  })
+}
+
+func UserStats(c *gin.Context){
+  u := c.MustGet("contextUser").(models.User)
+
+  helpers.Render(c, "stats.html", gin.H{
+    "title": u.Auth().Name,
+    "backLink": u.ProfileLink(),
+    "stats": models.UserStats(u),
+    "perUser": true,
+  })
+}
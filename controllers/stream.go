@@ -0,0 +1,111 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/player"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+func icyMetadata(title string) []byte{
+  /*
+    A length byte counting 16 byte blocks, followed by the padded metadata
+  */
+  title = strings.Replace(title, "'", "", -1)
+  meta := []byte(fmt.Sprintf("StreamTitle='%s';", title))
+  blocks := (len(meta) + 15)/16
+  if blocks > 255{
+    blocks = 255
+    meta = meta[:255*16]
+  }
+
+  block := make([]byte, 1 + blocks*16)
+  block[0] = byte(blocks)
+  copy(block[1:], meta)
+  return block
+}
+
+func RoomStream(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  if !checkInvite(c, room, optionalUser(c), c.Query("invite")){
+    return
+  }
+
+  if !room.UsesStream(){
+    helpers.Send404(c, "This room does not have a stream")
+    return
+  }
+
+  sendMeta := c.Request.Header.Get("Icy-MetaData") == "1"
+
+  c.Header("Content-Type", "audio/mpeg")
+  c.Header("Cache-Control", "no-cache")
+  c.Header("icy-name", room.Name)
+  if sendMeta{
+    c.Header("icy-metaint", strconv.Itoa(player.IcyMetaInt))
+  }
+  c.Status(200)
+  c.Writer.WriteHeaderNow()
+  c.Writer.Flush()
+
+  station := player.StationFor(room.ID)
+  listener := station.Listen()
+  defer station.Unlisten(listener)
+
+  log.WithFields(log.Fields{
+    "roomId": room.ID,
+    "listeners": station.Listeners(),
+  }).Debug("Stream listener connected")
+
+  closed := c.Writer.CloseNotify()
+  untilMeta := player.IcyMetaInt
+  lastTitle := ""
+
+  for{
+    select{
+    case <-closed:
+      return
+    case data, open := <-listener.Audio:
+      if !open{
+        return
+      }
+
+      for len(data) > 0{
+        n := len(data)
+        if sendMeta && n > untilMeta{
+          n = untilMeta
+        }
+
+        if _, err := c.Writer.Write(data[:n]); err != nil{
+          return
+        }
+        data = data[n:]
+
+        if !sendMeta{
+          continue
+        }
+        untilMeta -= n
+        if untilMeta == 0{
+          // Only send the title when it changes, otherwise an empty block
+          meta := []byte{0}
+          if title := station.Title(); title != lastTitle{
+            meta = icyMetadata(title)
+            lastTitle = title
+          }
+          if _, err := c.Writer.Write(meta); err != nil{
+            return
+          }
+          untilMeta = player.IcyMetaInt
+        }
+      }
+      c.Writer.Flush()
+    }
+  }
+}
@@ -6,6 +6,7 @@ import(
   "github.com/samarudge/jukebox/models"
   "github.com/samarudge/jukebox/db"
   "fmt"
+  "strings"
   log "github.com/Sirupsen/logrus"
 )
 
@@ -23,11 +24,16 @@ func UserContext(c *gin.Context){
 
 func UserInfo(c *gin.Context){
   u := c.MustGet("contextUser").(models.User)
+  authUser := c.MustGet("authUser").(models.User)
 
   helpers.Render(c, "users/info.html", gin.H{
     "user": u,
     "auth": u.Auth(),
     "spotify": u.GetSpotify(),
+    "roomHistory": u.RoomHistory(20),
+    "tokens": u.Tokens(),
+    "scopes": scopeOptions(),
+    "isSelf": u.ID == authUser.ID,
   })
 }
 
@@ -52,7 +58,7 @@ func UserUpdate(c *gin.Context){
 
   d := db.Db()
   d.Save(&u)
-  c.Redirect(302, u.ProfileLink())
+  helpers.Respond(c, u.ProfileLink(), userJson(u))
 }
 
 func UserList(c *gin.Context){
@@ -64,3 +70,78 @@ func UserList(c *gin.Context){
     "users": users,
   })
 }
+
+type scopeOption struct{
+  Scope       string
+  Description string
+}
+
+func scopeOptions() []scopeOption{
+  var options []scopeOption
+  for _, scope := range models.Scopes{
+    options = append(options, scopeOption{
+      Scope: scope,
+      Description: models.ScopeDescriptions[scope],
+    })
+  }
+  return options
+}
+
+func UserTokenCreate(c *gin.Context){
+  u := c.MustGet("contextUser").(models.User)
+  authUser := c.MustGet("authUser").(models.User)
+  if u.ID != authUser.ID{
+    // Admins can revoke other people's tokens but not act as them
+    helpers.Send403(c, "You can only create API tokens for yourself")
+    return
+  }
+
+  name := strings.TrimSpace(c.PostForm("name"))
+  if name == ""{
+    helpers.Send400(c, "Give the token a name so you can tell it apart later")
+    return
+  }
+
+  c.Request.ParseForm()
+  scopes := c.Request.PostForm["scope"]
+  if len(scopes) == 0{
+    helpers.Send400(c, "Pick at least one scope for the token")
+    return
+  }
+  for _, scope := range scopes{
+    if !models.ValidScope(scope){
+      helpers.Send400(c, fmt.Sprintf("Unknown scope %s", scope))
+      return
+    }
+    if scope == models.ScopeAdmin && !u.IsAdmin{
+      helpers.Send403(c, "Only admins can create tokens with the admin scope")
+      return
+    }
+  }
+
+  t, secret, err := u.CreateToken(name, scopes)
+  if err != nil{
+    helpers.Send500(c, fmt.Sprintf("%s (%s)", "Could not create token", err))
+    return
+  }
+
+  helpers.Render(c, "users/token.html", gin.H{
+    "user": u,
+    "token": t,
+    "secret": secret,
+  })
+}
+
+func UserTokenRevoke(c *gin.Context){
+  u := c.MustGet("contextUser").(models.User)
+
+  t := models.ApiToken{}
+  t.ById(c.Param("tokenId"))
+  if t.ID == 0 || t.UserID != uint64(u.ID){
+    helpers.Send404(c, "Token not found")
+    return
+  }
+
+  t.Revoke()
+  c.Redirect(302, u.ProfileLink())
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import(
+  "github.com/gin-gonic/gin"
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/helpers"
+  "github.com/samarudge/jukebox/models"
+  "strconv"
+  "strings"
+)
+
+type webhookEventOption struct{
+  Event       string
+  Description string
+}
+
+func webhookEventOptions() []webhookEventOption{
+  var options []webhookEventOption
+  for _, event := range models.WebhookEvents{
+    options = append(options, webhookEventOption{
+      Event: event,
+      Description: models.WebhookEventDescriptions[event],
+    })
+  }
+  return options
+}
+
+func RoomWebhookCreate(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.IsOwner(u){
+    helpers.Send403(c, "Only the room's owners can add webhooks")
+    return
+  }
+
+  c.Request.ParseForm()
+  _, err := room.AddWebhook(u, strings.TrimSpace(c.PostForm("url")), c.Request.PostForm["event"])
+  if err != nil{
+    helpers.Send400(c, err.Error())
+    return
+  }
+  c.Redirect(302, room.Url())
+}
+
+func RoomWebhookDelete(c *gin.Context){
+  room, found := loadRoom(c)
+  if !found{
+    return
+  }
+
+  u := c.MustGet("authUser").(models.User)
+  if !room.IsOwner(u){
+    helpers.Send403(c, "Only the room's owners can remove webhooks")
+    return
+  }
+
+  d := db.Db()
+  h := models.Webhook{}
+  h.ById(c.Param("webhookId"))
+  if d.NewRecord(h) || h.RoomID != uint64(room.ID){
+    helpers.Send404(c, "Webhook not found")
+    return
+  }
+
+  h.Delete()
+  c.Redirect(302, room.Url())
+}
+
+func AdminWebhookRetry(c *gin.Context){
+  d := db.Db()
+  w := models.WebhookDelivery{}
+  w.ById(c.Param("deliveryId"))
+  if d.NewRecord(w){
+    helpers.Send404(c, "Delivery not found")
+    return
+  }
+  if !w.Failed(){
+    helpers.Send400(c, "Only failed deliveries can be retried")
+    return
+  }
+
+  h := models.Webhook{}
+  h.ById(strconv.FormatUint(w.WebhookID, 10))
+  if d.NewRecord(h){
+    helpers.Send400(c, "The webhook for this delivery has been deleted")
+    return
+  }
+
+  w.Retry()
+  c.Redirect(302, "/admin")
+}
@@ -0,0 +1,197 @@
+package events
+
+import(
+  log "github.com/Sirupsen/logrus"
+  "sync"
+  "time"
+)
+
+/*
+  Rooms publish what changes in them here so connected browsers can be told
+  without reloading. Each room keeps a short in-memory log so clients that
+  reconnect can catch up, anyone who falls off the end of it gets a fresh
+  snapshot instead
+*/
+
+// Subscribers that fall this far behind are dropped rather than blocking publishers
+const subscriberBuffer = 64
+
+// How many events each room keeps for replay
+const logSize = 256
+
+// Keep publishing for a while after the last subscriber leaves so they can catch up on reconnect
+var ReplayWindow = time.Minute*5
+
+type Event struct{
+  ID      uint64      `json:"id"`
+  Type    string      `json:"type"`
+  RoomID  uint        `json:"roomId"`
+  Time    time.Time   `json:"time"`
+  Data    interface{} `json:"data"`
+}
+
+type Subscription struct{
+  RoomID  uint
+  // 0 for visitors who aren't signed in
+  UserID  uint
+  Events  chan Event
+}
+
+type roomLog struct{
+  events    []Event
+  // Anyone who last saw an event before this has missed something
+  dropped   uint64
+  lastLeft  time.Time
+}
+
+var subscriptions = make(map[uint]map[*Subscription]bool)
+var logs = make(map[uint]*roomLog)
+var subscriptionsLock sync.Mutex
+
+// IDs start from the time so ones from before a restart are never reused
+var startId = uint64(time.Now().UnixNano()/int64(time.Millisecond))*1000
+var lastId = startId
+
+func Subscribe(roomId uint, userId uint) *Subscription{
+  s, _, _, _ := SubscribeFrom(roomId, userId, 0)
+  return s
+}
+
+func SubscribeFrom(roomId uint, userId uint, after uint64) (*Subscription, []Event, uint64, bool){
+  /*
+    Subscribe and return anything logged since the given event ID, in one go
+    so nothing can be published in between. The bool is false when events
+    after that ID have already been dropped from the log
+  */
+  subscriptionsLock.Lock()
+  defer subscriptionsLock.Unlock()
+
+  s := &Subscription{
+    RoomID: roomId,
+    UserID: userId,
+    Events: make(chan Event, subscriberBuffer),
+  }
+  if subscriptions[roomId] == nil{
+    subscriptions[roomId] = make(map[*Subscription]bool)
+  }
+  subscriptions[roomId][s] = true
+
+  if after < startId{
+    return s, nil, lastId, false
+  }
+
+  var missed []Event
+  l := logs[roomId]
+  if l == nil{
+    // Nothing has happened in the room since startup
+    return s, nil, lastId, true
+  }
+  for _, e := range l.events{
+    if e.ID > after{
+      missed = append(missed, e)
+    }
+  }
+  return s, missed, lastId, after >= l.dropped
+}
+
+func Unsubscribe(s *Subscription){
+  subscriptionsLock.Lock()
+  defer subscriptionsLock.Unlock()
+  unsubscribe(s)
+}
+
+func unsubscribe(s *Subscription){
+  if !subscriptions[s.RoomID][s]{
+    return
+  }
+
+  delete(subscriptions[s.RoomID], s)
+  if len(subscriptions[s.RoomID]) == 0{
+    delete(subscriptions, s.RoomID)
+    if l := logs[s.RoomID]; l != nil{
+      l.lastLeft = time.Now()
+    }
+  }
+  close(s.Events)
+}
+
+func CloseUser(roomId uint, userId uint){
+  /*
+    Ends a user's subscriptions to the room when they leave or are removed,
+    clients reconnect and have their access checked again
+  */
+  if userId == 0{
+    return
+  }
+
+  subscriptionsLock.Lock()
+  defer subscriptionsLock.Unlock()
+
+  for s := range subscriptions[roomId]{
+    if s.UserID == userId{
+      unsubscribe(s)
+    }
+  }
+}
+
+func Watched(roomId uint) bool{
+  /*
+    Whether anything needs to hear about changes to the room, either someone
+    is connected or someone recently was and may reconnect
+  */
+  subscriptionsLock.Lock()
+  defer subscriptionsLock.Unlock()
+
+  if len(subscriptions[roomId]) > 0{
+    return true
+  }
+
+  l := logs[roomId]
+  if l == nil{
+    return false
+  }
+  if time.Since(l.lastLeft) < ReplayWindow{
+    return true
+  }
+
+  // Changes are about to go unlogged, so nobody can catch up from here
+  l.events = nil
+  l.dropped = lastId
+  return false
+}
+
+func Publish(roomId uint, eventType string, data interface{}){
+  subscriptionsLock.Lock()
+  defer subscriptionsLock.Unlock()
+
+  lastId++
+  e := Event{
+    ID: lastId,
+    Type: eventType,
+    RoomID: roomId,
+    Time: time.Now().UTC(),
+    Data: data,
+  }
+
+  l := logs[roomId]
+  if l == nil{
+    l = &roomLog{}
+    logs[roomId] = l
+  }
+  l.events = append(l.events, e)
+  if len(l.events) > logSize{
+    l.dropped = l.events[len(l.events)-logSize-1].ID
+    l.events = l.events[len(l.events)-logSize:]
+  }
+
+  for s := range subscriptions[roomId]{
+    select{
+    case s.Events <- e:
+    default:
+      log.WithFields(log.Fields{
+        "roomId": roomId,
+      }).Warning("Dropping slow event subscriber")
+      unsubscribe(s)
+    }
+  }
+}
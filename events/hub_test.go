@@ -0,0 +1,46 @@
+package events
+
+import(
+  "testing"
+)
+
+func closed(s *Subscription) bool{
+  select{
+  case _, open := <-s.Events:
+    return !open
+  default:
+    return false
+  }
+}
+
+func TestCloseUser(t *testing.T){
+  leaving := Subscribe(1, 10)
+  otherTab := Subscribe(1, 10)
+  staying := Subscribe(1, 11)
+  visitor := Subscribe(1, 0)
+  otherRoom := Subscribe(2, 10)
+  defer Unsubscribe(staying)
+  defer Unsubscribe(visitor)
+  defer Unsubscribe(otherRoom)
+
+  CloseUser(1, 10)
+  if !closed(leaving) || !closed(otherTab){
+    t.Error("The user's subscriptions to the room are still open")
+  }
+  if closed(staying) || closed(visitor) || closed(otherRoom){
+    t.Error("Closed someone else's subscription")
+  }
+
+  // Visitors who aren't signed in all share user 0
+  CloseUser(1, 0)
+  if closed(visitor){
+    t.Error("Closed a visitor's subscription")
+  }
+
+  // Already closed subscriptions can still be unsubscribed
+  Unsubscribe(leaving)
+  Publish(1, "test", nil)
+  if e := <-staying.Events; e.Type != "test"{
+    t.Errorf("Unexpected event %s", e.Type)
+  }
+}
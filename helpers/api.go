@@ -0,0 +1,65 @@
+package helpers
+
+import(
+  "github.com/gin-gonic/gin"
+  "encoding/json"
+  "fmt"
+  "net/url"
+  "strings"
+)
+
+const ApiPrefix = "/api/v1"
+
+func IsApi(c *gin.Context) bool{
+  return strings.HasPrefix(c.Request.URL.Path, ApiPrefix + "/")
+}
+
+func Respond(c *gin.Context, redirect string, obj interface{}){
+  /*
+    Actions shared between the site and the API redirect browsers back to a
+    page, API clients get the result instead
+  */
+  if IsApi(c){
+    c.JSON(200, obj)
+  } else {
+    c.Redirect(302, redirect)
+  }
+}
+
+func JsonForm() gin.HandlerFunc{
+  /*
+    Lets API clients send a JSON object instead of a form, the controllers
+    read it with PostForm either way
+  */
+  return func(c *gin.Context){
+    if !strings.HasPrefix(c.Request.Header.Get("Content-Type"), "application/json"){
+      c.Next()
+      return
+    }
+
+    var body map[string]interface{}
+    decoder := json.NewDecoder(c.Request.Body)
+    // Large numbers would otherwise come out as 2e+06
+    decoder.UseNumber()
+    if err := decoder.Decode(&body); err != nil{
+      Send400(c, fmt.Sprintf("%s (%s)", "Could not decode JSON body", err))
+      return
+    }
+
+    form := url.Values{}
+    for key, value := range body{
+      switch v := value.(type){
+      case []interface{}:
+        for _, item := range v{
+          form.Add(key, fmt.Sprint(item))
+        }
+      case nil:
+      default:
+        form.Set(key, fmt.Sprint(v))
+      }
+    }
+    c.Request.PostForm = form
+    c.Request.Form = form
+    c.Next()
+  }
+}
@@ -10,6 +10,7 @@ import(
   "strconv"
   "net/url"
   "fmt"
+  "strings"
 )
 
 func ClearAuthCookie(c *gin.Context){
@@ -88,6 +89,92 @@ func RequireAdmin() gin.HandlerFunc{
   }
 }
 
+func setAuthUser(c *gin.Context, u models.User){
+  d := db.Db()
+  authUserId := strconv.FormatUint(uint64(u.ID), 10)
+
+  c.Set("authUserId", authUserId)
+  c.Set("authUser", u)
+
+  if u.IsAdmin{
+    c.Set("isAdmin", true)
+  } else {
+    c.Set("isAdmin", false)
+  }
+
+  room := models.Room{}
+  room.ById(strconv.FormatUint(uint64(u.RoomID), 10))
+  if !d.NewRecord(room){
+    log.WithFields(log.Fields{
+      "userId": u.ID,
+      "roomId": room.ID,
+      "room": room.Name,
+    }).Debug("Loaded active room")
+    c.Set("currentRoom", room)
+  }
+
+  if time.Now().UTC().Sub(u.LastSeen).Minutes() > 5 {
+    log.WithFields(log.Fields{
+      "User": authUserId,
+    }).Debug("Updating User Last Seen")
+
+    u.LastSeen = time.Now().UTC()
+
+    d.Save(&u)
+  }
+}
+
+func bearerToken(c *gin.Context) (string, bool){
+  header := c.Request.Header.Get("Authorization")
+  if !strings.HasPrefix(header, "Bearer "){
+    return "", false
+  }
+  return strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")), true
+}
+
+func tokenAuth(c *gin.Context, secret string) bool{
+  /*
+    Tokens only work against the API, where every route checks the token's
+    scopes, so they can't be used to get at anything through the site
+  */
+  if !IsApi(c){
+    Send403(c, "API tokens can only be used with the API")
+    return false
+  }
+
+  d := db.Db()
+  t := models.ApiToken{}
+  t.BySecret(secret)
+  u := t.User()
+  if d.NewRecord(t) || d.NewRecord(u) || !u.Auth().AuthValid{
+    log.WithFields(log.Fields{
+      "tokenId": t.ID,
+    }).Warning("Invalid API token")
+    Send403(c, "Invalid API token")
+    return false
+  }
+
+  t.Used()
+  c.Set("apiToken", t)
+  setAuthUser(c, u)
+  return true
+}
+
+func RequireScope(scope string) gin.HandlerFunc{
+  /*
+    Browser sessions can do anything the user can, tokens only what they
+    were created for
+  */
+  return func(c *gin.Context){
+    tokenInterface, _ := c.Get("apiToken")
+    if tokenInterface != nil && !tokenInterface.(models.ApiToken).HasScope(scope){
+      Send403(c, fmt.Sprintf("This API token does not have the %s scope", scope))
+    } else {
+      c.Next()
+    }
+  }
+}
+
 func Auth() gin.HandlerFunc{
   /*
     Process authentication data
@@ -95,7 +182,11 @@ func Auth() gin.HandlerFunc{
 
   return func(c *gin.Context) {
     authUserCookie, err := c.Cookie("jukebox_user")
-    if err == nil{
+    if token, hasToken := bearerToken(c); hasToken{
+      if !tokenAuth(c, token){
+        return
+      }
+    } else if err == nil{
       authUserId, err := VerifyValue(authUserCookie)
 
       d := db.Db()
@@ -126,35 +217,7 @@ func Auth() gin.HandlerFunc{
           }
         }
 
-        c.Set("authUserId", authUserId)
-        c.Set("authUser", u)
-
-        if u.IsAdmin{
-          c.Set("isAdmin", true)
-        } else {
-          c.Set("isAdmin", false)
-        }
-
-        room := models.Room{}
-        room.ById(strconv.FormatUint(uint64(u.RoomID), 10))
-        if !d.NewRecord(room){
-          log.WithFields(log.Fields{
-            "userId": u.ID,
-            "roomId": room.ID,
-            "room": room.Name,
-          }).Debug("Loaded active room")
-          c.Set("currentRoom", room)
-        }
-
-        if time.Now().UTC().Sub(u.LastSeen).Minutes() > 5 {
-          log.WithFields(log.Fields{
-            "User": authUserId,
-          }).Debug("Updating User Last Seen")
-
-          u.LastSeen = time.Now().UTC()
-
-          d.Save(&u)
-        }
+        setAuthUser(c, u)
       }
     }
 
@@ -5,33 +5,44 @@ import(
   log "github.com/Sirupsen/logrus"
 )
 
+func sendError(c *gin.Context, status int, title string, err string, showLogin bool){
+  /*
+    API clients get the same errors as a JSON object instead of a page
+  */
+  c.Status(status)
+  if IsApi(c){
+    c.JSON(status, gin.H{
+      "error": gin.H{
+        "status": status,
+        "title": title,
+        "message": err,
+      },
+    })
+  } else {
+    Render(c, "error.html", gin.H{
+      "errorTitle": title,
+      "errorDetails": err,
+      "showLogin": showLogin,
+    })
+  }
+  c.Abort()
+}
+
 func Send500(c *gin.Context, err string){
-  c.Status(500)
-  Render(c, "error.html", gin.H{
-    "errorTitle": "Application Error",
-    "errorDetails": err,
-  })
+  sendError(c, 500, "Application Error", err, false)
   log.WithFields(log.Fields{
     "error": err,
   }).Error("Server error")
-  c.Abort()
+}
+
+func Send400(c *gin.Context, err string){
+  sendError(c, 400, "Invalid Request", err, false)
 }
 
 func Send403(c *gin.Context, err string){
-  c.Status(403)
-  Render(c, "error.html", gin.H{
-    "errorTitle": "Authorization Error",
-    "errorDetails": err,
-    "showLogin": true,
-  })
-  c.Abort()
+  sendError(c, 403, "Authorization Error", err, true)
 }
 
 func Send404(c *gin.Context, err string){
-  c.Status(404)
-  Render(c, "error.html", gin.H{
-    "errorTitle": "Not Found",
-    "errorDetails": err,
-  })
-  c.Abort()
+  sendError(c, 404, "Not Found", err, false)
 }
@@ -0,0 +1,66 @@
+package helpers
+
+import(
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/db"
+  "fmt"
+  "net/url"
+  "strconv"
+  "strings"
+  "time"
+)
+
+/*
+  Invites are signed "roomId|expiry|secret" values, regenerating the room's
+  invite secret invalidates every invite handed out before it
+*/
+
+func InviteToken(room models.Room, expires time.Time) string{
+  return SignValue(fmt.Sprintf("%d|%d|%s", room.ID, expires.Unix(), room.InviteSecret))
+}
+
+func InviteLink(room models.Room, expires time.Time) string{
+  u := url.URL{}
+  u.Path = room.Url()
+  q := u.Query()
+  q.Set("invite", InviteToken(room, expires))
+  u.RawQuery = q.Encode()
+  return u.String()
+}
+
+func VerifyInvite(token string, room models.Room) error{
+  if token == ""{
+    return fmt.Errorf("This room is private, you need an invite to join")
+  }
+
+  value, err := VerifyValue(token)
+  if err != nil{
+    return fmt.Errorf("Invalid invite")
+  }
+
+  parts := strings.Split(value, "|")
+  if len(parts) != 3{
+    return fmt.Errorf("Invalid invite")
+  }
+
+  expires, err := strconv.ParseInt(parts[1], 10, 64)
+  if err != nil{
+    return fmt.Errorf("Invalid invite")
+  }
+
+  d := db.Db()
+  invitedRoom := models.Room{}
+  invitedRoom.ById(parts[0])
+  if d.NewRecord(invitedRoom) || invitedRoom.ID != room.ID{
+    return fmt.Errorf("This invite is for a different room")
+  }
+
+  if time.Now().Unix() > expires{
+    return fmt.Errorf("This invite has expired")
+  }
+
+  if parts[2] != room.InviteSecret{
+    return fmt.Errorf("This invite has been revoked")
+  }
+  return nil
+}
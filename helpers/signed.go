@@ -24,6 +24,15 @@ func SignValue(val string) string{
 
 func VerifyValue(signed string) (string, error){
   valueParts := strings.Split(signed, "|")
+  if len(valueParts) != 2{
+    // Invites come straight from the query string, so anything can turn up
+    log.WithFields(log.Fields{
+      "source": signed,
+      "type": "format",
+      "err": "",
+    }).Warning("Invalid signed value")
+    return "", fmt.Errorf("Invalid signed value")
+  }
   val, err := base64.StdEncoding.DecodeString(valueParts[0])
   if err != nil {
     log.WithFields(log.Fields{
@@ -31,7 +40,7 @@ func VerifyValue(signed string) (string, error){
       "type": "base64",
       "err": err,
     }).Warning("Invalid signed value")
-    return "", fmt.Errorf("Base64 Decode Error:", err)
+    return "", fmt.Errorf("Base64 Decode Error: %s", err)
   }
 
   targetHash := getHash(string(val))
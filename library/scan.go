@@ -0,0 +1,136 @@
+package library
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/models"
+  log "github.com/Sirupsen/logrus"
+  "os"
+  "path/filepath"
+  "strings"
+  "sync"
+  "time"
+)
+
+// Directory of music files, set from config
+var Root string
+
+var scanLock sync.Mutex
+
+func Configured() bool{
+  return Root != ""
+}
+
+func FilePath(t models.LibraryTrack) string{
+  return filepath.Join(Root, t.Path)
+}
+
+func Scan() error{
+  /*
+    Index every supported file under Root, only re-reading tags for files
+    that changed since the last scan
+  */
+  scanLock.Lock()
+  defer scanLock.Unlock()
+
+  d := db.Db()
+  seen := make(map[string]bool)
+  started := time.Now()
+  updated := 0
+  unreadable := 0
+
+  err := filepath.Walk(Root, func(path string, info os.FileInfo, err error) error{
+    if err != nil{
+      // Without the root every track would look deleted
+      if path == Root{
+        return err
+      }
+      log.WithFields(log.Fields{
+        "path": path,
+        "error": err,
+      }).Warning("Could not read library path")
+      unreadable++
+      return nil
+    }
+    if info.IsDir(){
+      return nil
+    }
+    if _, supported := Formats[strings.ToLower(filepath.Ext(path))]; !supported{
+      return nil
+    }
+
+    relPath, err := filepath.Rel(Root, path)
+    if err != nil{
+      return err
+    }
+    seen[relPath] = true
+
+    t := models.LibraryTrack{}
+    t.ByPath(relPath)
+    if !d.NewRecord(t) && t.ModTime.Equal(info.ModTime().UTC()){
+      return nil
+    }
+
+    tags, err := ReadTags(path)
+    if err != nil{
+      log.WithFields(log.Fields{
+        "path": path,
+        "error": err,
+      }).Warning("Could not read tags")
+    }
+
+    if d.NewRecord(t){
+      t.Model = gorm.Model{}
+    }
+    t.Path = relPath
+    t.Title = tags.Title
+    t.Artist = tags.Artist
+    t.Album = tags.Album
+    t.DurationMs = int(tags.Duration/time.Millisecond)
+    t.Format = tags.Format
+    t.ModTime = info.ModTime().UTC()
+    d.Save(&t)
+    updated++
+    return nil
+  })
+  if err != nil{
+    return err
+  }
+
+  // Tracks under a path that couldn't be read may still be there, keep everything until a clean scan
+  var tracks []models.LibraryTrack
+  if unreadable == 0{
+    d.Find(&tracks)
+  }
+  removed := 0
+  for i := range tracks{
+    if !seen[tracks[i].Path]{
+      d.Unscoped().Delete(&tracks[i])
+      removed++
+    }
+  }
+
+  log.WithFields(log.Fields{
+    "root": Root,
+    "files": len(seen),
+    "updated": updated,
+    "removed": removed,
+    "unreadable": unreadable,
+    "duration": time.Since(started),
+  }).Info("Scanned music library")
+  return nil
+}
+
+func JobScan(){
+  if !Configured(){
+    return
+  }
+
+  err := Scan()
+  if err != nil{
+    log.WithFields(log.Fields{
+      "root": Root,
+      "error": err,
+    }).Error("Library scan failed")
+  }
+}
@@ -0,0 +1,54 @@
+package library
+
+import(
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/testdb"
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+func libraryPaths() map[string]bool{
+  d := db.Db()
+  var tracks []models.LibraryTrack
+  d.Find(&tracks)
+  paths := make(map[string]bool)
+  for _, t := range tracks{
+    paths[t.Path] = true
+  }
+  return paths
+}
+
+func TestScanMissingRoot(t *testing.T){
+  defer testdb.Open(t)()
+  dir, cleanup := fixtureDir(t)
+  defer cleanup()
+  oldRoot := Root
+  defer func(){ Root = oldRoot }()
+
+  Root = dir
+  writeFixture(t, dir, "kept.mp3", nil)
+  removed := writeFixture(t, dir, "removed.mp3", nil)
+  if err := Scan(); err != nil{
+    t.Fatal(err)
+  }
+
+  // A clean scan drops files that have gone
+  os.Remove(removed)
+  if err := Scan(); err != nil{
+    t.Fatal(err)
+  }
+  if paths := libraryPaths(); len(paths) != 1 || !paths["kept.mp3"]{
+    t.Fatalf("Library has %v after a file was removed", paths)
+  }
+
+  // An unmounted or mistyped root is an error, not an empty library
+  Root = filepath.Join(dir, "missing")
+  if err := Scan(); err == nil{
+    t.Error("Scanned a root that doesn't exist")
+  }
+  if paths := libraryPaths(); !paths["kept.mp3"]{
+    t.Errorf("Library has %v after scanning a missing root", paths)
+  }
+}
@@ -0,0 +1,533 @@
+package library
+
+import(
+  "bytes"
+  "encoding/binary"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "time"
+  "unicode/utf16"
+)
+
+type Tags struct{
+  Title     string
+  Artist    string
+  Album     string
+  Duration  time.Duration
+  Format    string
+}
+
+var Formats = map[string]string{
+  ".mp3": "mp3",
+  ".flac": "flac",
+  ".ogg": "ogg",
+  ".oga": "ogg",
+  ".opus": "ogg",
+}
+
+func ReadTags(path string) (Tags, error){
+  format, supported := Formats[strings.ToLower(filepath.Ext(path))]
+  if !supported{
+    return Tags{}, fmt.Errorf("Unsupported file type %s", filepath.Ext(path))
+  }
+
+  f, err := os.Open(path)
+  if err != nil{
+    return Tags{}, err
+  }
+  defer f.Close()
+
+  var tags Tags
+  switch format{
+  case "mp3":
+    tags, err = readMp3(f)
+  case "flac":
+    tags, err = readFlac(f)
+  case "ogg":
+    tags, err = readOgg(f)
+  }
+  tags.Format = format
+
+  // Fall back to the file name so every track can be found
+  if tags.Title == ""{
+    tags.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+  }
+  return tags, err
+}
+
+/*
+  MP3
+*/
+
+func syncsafe(b []byte) int{
+  return int(b[0])<<21 | int(b[1])<<14 | int(b[2])<<7 | int(b[3])
+}
+
+func decodeText(b []byte) string{
+  if len(b) == 0{
+    return ""
+  }
+
+  encoding, b := b[0], b[1:]
+  var text string
+  switch encoding{
+  case 1, 2:
+    bigEndian := encoding == 2
+    if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF{
+      bigEndian, b = true, b[2:]
+    } else if len(b) >= 2 && b[0] == 0xFF && b[1] == 0xFE{
+      bigEndian, b = false, b[2:]
+    }
+    units := make([]uint16, len(b)/2)
+    for i := range units{
+      if bigEndian{
+        units[i] = binary.BigEndian.Uint16(b[i*2:])
+      } else {
+        units[i] = binary.LittleEndian.Uint16(b[i*2:])
+      }
+    }
+    text = string(utf16.Decode(units))
+  case 3:
+    text = string(b)
+  default:
+    runes := make([]rune, len(b))
+    for i, c := range b{
+      runes[i] = rune(c)
+    }
+    text = string(runes)
+  }
+
+  // Multiple values are null separated, keep the first
+  if i := strings.IndexRune(text, 0); i >= 0{
+    text = text[:i]
+  }
+  return strings.TrimSpace(text)
+}
+
+func readId3v2(f io.ReadSeeker, tags *Tags) (int64, error){
+  /*
+    Returns the offset the audio starts at
+  */
+  header := make([]byte, 10)
+  if _, err := io.ReadFull(f, header); err != nil{
+    return 0, err
+  }
+  if string(header[:3]) != "ID3"{
+    return 0, nil
+  }
+
+  version := header[3]
+  size := syncsafe(header[6:10])
+  // Read no more than the file has, a corrupt size can claim up to 256MB
+  data, err := ioutil.ReadAll(io.LimitReader(f, int64(size)))
+  if err != nil{
+    return 0, err
+  }
+  if len(data) < size{
+    return 0, io.ErrUnexpectedEOF
+  }
+
+  if header[5]&0x40 != 0 && version > 2 && len(data) >= 4{
+    extSize := int(binary.BigEndian.Uint32(data[:4])) + 4
+    if version == 4{
+      extSize = syncsafe(data[:4])
+    }
+    if extSize < len(data){
+      data = data[extSize:]
+    }
+  }
+
+  idLen, headerLen := 4, 10
+  if version == 2{
+    idLen, headerLen = 3, 6
+  }
+
+  for len(data) >= headerLen && data[0] != 0{
+    id := string(data[:idLen])
+    var frameSize int
+    switch version{
+    case 2:
+      frameSize = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+    case 3:
+      frameSize = int(binary.BigEndian.Uint32(data[4:8]))
+    default:
+      frameSize = syncsafe(data[4:8])
+    }
+    if frameSize < 0 || headerLen+frameSize > len(data){
+      break
+    }
+    frame := data[headerLen:headerLen+frameSize]
+    data = data[headerLen+frameSize:]
+
+    switch id{
+    case "TIT2", "TT2":
+      tags.Title = decodeText(frame)
+    case "TPE1", "TP1":
+      tags.Artist = decodeText(frame)
+    case "TALB", "TAL":
+      tags.Album = decodeText(frame)
+    case "TLEN", "TLE":
+      ms, err := strconv.Atoi(decodeText(frame))
+      if err == nil{
+        tags.Duration = time.Duration(ms)*time.Millisecond
+      }
+    }
+  }
+
+  return int64(10 + size), nil
+}
+
+func readId3v1(f io.ReadSeeker, tags *Tags) bool{
+  tag := make([]byte, 128)
+  if _, err := f.Seek(-128, 2); err != nil{
+    return false
+  }
+  if _, err := io.ReadFull(f, tag); err != nil || string(tag[:3]) != "TAG"{
+    return false
+  }
+
+  field := func(b []byte) string{
+    return strings.TrimSpace(strings.TrimRight(string(b), "\x00"))
+  }
+  if tags.Title == ""{
+    tags.Title = field(tag[3:33])
+  }
+  if tags.Artist == ""{
+    tags.Artist = field(tag[33:63])
+  }
+  if tags.Album == ""{
+    tags.Album = field(tag[63:93])
+  }
+  return true
+}
+
+var mpegBitrates = map[string][]int{
+  "1-1": {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+  "1-2": {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+  "1-3": {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+  "2-1": {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+  "2-2": {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+  "2-3": {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+var mpegSampleRates = map[int][]int{
+  1: {44100, 48000, 32000},
+  2: {22050, 24000, 16000},
+  25: {11025, 12000, 8000},
+}
+
+type mpegFrame struct{
+  Bitrate       int
+  SampleRate    int
+  Samples       int
+  // Number of frames from a Xing/Info header, 0 for CBR files
+  Frames        int
+}
+
+func parseMpegFrame(b []byte) (mpegFrame, bool){
+  frame := mpegFrame{}
+  if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0{
+    return frame, false
+  }
+
+  version := map[byte]int{0: 25, 2: 2, 3: 1}[(b[1]>>3)&0x03]
+  layer := map[byte]int{1: 3, 2: 2, 3: 1}[(b[1]>>1)&0x03]
+  bitrateIdx := int(b[2]>>4)
+  rateIdx := int((b[2]>>2)&0x03)
+  if version == 0 || layer == 0 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3{
+    return frame, false
+  }
+
+  tableVersion := version
+  if version == 25{
+    tableVersion = 2
+  }
+  frame.Bitrate = mpegBitrates[fmt.Sprintf("%d-%d", tableVersion, layer)][bitrateIdx]*1000
+  frame.SampleRate = mpegSampleRates[version][rateIdx]
+
+  switch{
+  case layer == 1:
+    frame.Samples = 384
+  case layer == 3 && version != 1:
+    frame.Samples = 576
+  default:
+    frame.Samples = 1152
+  }
+
+  mono := (b[3]>>6) == 3
+  sideInfo := 32
+  switch{
+  case version == 1 && mono:
+    sideInfo = 17
+  case version != 1 && !mono:
+    sideInfo = 17
+  case version != 1 && mono:
+    sideInfo = 9
+  }
+
+  xing := 4 + sideInfo
+  if len(b) >= xing+12{
+    tag := string(b[xing:xing+4])
+    if (tag == "Xing" || tag == "Info") && b[xing+7]&0x01 != 0{
+      frame.Frames = int(binary.BigEndian.Uint32(b[xing+8:xing+12]))
+    }
+  }
+  return frame, true
+}
+
+func readMp3(f *os.File) (Tags, error){
+  tags := Tags{}
+  audioStart, err := readId3v2(f, &tags)
+  if err != nil{
+    return tags, err
+  }
+
+  info, err := f.Stat()
+  if err != nil{
+    return tags, err
+  }
+  audioEnd := info.Size()
+  if readId3v1(f, &tags){
+    audioEnd -= 128
+  }
+
+  if tags.Duration > 0{
+    return tags, nil
+  }
+
+  buf := make([]byte, 64*1024)
+  f.Seek(audioStart, 0)
+  n, _ := io.ReadFull(f, buf)
+  buf = buf[:n]
+
+  for i := 0; i < len(buf)-4; i++{
+    frame, found := parseMpegFrame(buf[i:])
+    if !found{
+      continue
+    }
+
+    if frame.Frames > 0{
+      tags.Duration = time.Duration(float64(frame.Frames*frame.Samples)/float64(frame.SampleRate)*float64(time.Second))
+    } else {
+      audioBytes := audioEnd - audioStart - int64(i)
+      tags.Duration = time.Duration(float64(audioBytes*8)/float64(frame.Bitrate)*float64(time.Second))
+    }
+    break
+  }
+  return tags, nil
+}
+
+/*
+  Vorbis comments, used by both FLAC and Ogg
+*/
+
+func readVorbisComment(b []byte, tags *Tags){
+  r := bytes.NewReader(b)
+  var length uint32
+
+  if binary.Read(r, binary.LittleEndian, &length) != nil{
+    return
+  }
+  if _, err := r.Seek(int64(length), 1); err != nil{
+    return
+  }
+
+  var count uint32
+  if binary.Read(r, binary.LittleEndian, &count) != nil{
+    return
+  }
+
+  for i := uint32(0); i < count; i++{
+    if binary.Read(r, binary.LittleEndian, &length) != nil || int(length) > r.Len(){
+      return
+    }
+    comment := make([]byte, length)
+    r.Read(comment)
+
+    parts := strings.SplitN(string(comment), "=", 2)
+    if len(parts) != 2{
+      continue
+    }
+    switch strings.ToUpper(parts[0]){
+    case "TITLE":
+      tags.Title = parts[1]
+    case "ARTIST":
+      tags.Artist = parts[1]
+    case "ALBUM":
+      tags.Album = parts[1]
+    }
+  }
+}
+
+/*
+  FLAC
+*/
+
+func readFlac(f *os.File) (Tags, error){
+  tags := Tags{}
+
+  // Some taggers put an ID3 tag in front of the stream
+  start, err := readId3v2(f, &tags)
+  if err != nil{
+    return tags, err
+  }
+  f.Seek(start, 0)
+
+  marker := make([]byte, 4)
+  if _, err := io.ReadFull(f, marker); err != nil || string(marker) != "fLaC"{
+    return tags, fmt.Errorf("Not a FLAC file")
+  }
+
+  header := make([]byte, 4)
+  for{
+    if _, err := io.ReadFull(f, header); err != nil{
+      return tags, err
+    }
+    last := header[0]&0x80 != 0
+    blockType := header[0]&0x7F
+    length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+    switch blockType{
+    case 0, 4:
+      block := make([]byte, length)
+      if _, err := io.ReadFull(f, block); err != nil{
+        return tags, err
+      }
+
+      if blockType == 4{
+        readVorbisComment(block, &tags)
+      } else if len(block) >= 18{
+        sampleRate := int(block[10])<<12 | int(block[11])<<4 | int(block[12])>>4
+        samples := int64(block[13]&0x0F)<<32 | int64(binary.BigEndian.Uint32(block[14:18]))
+        if sampleRate > 0{
+          tags.Duration = time.Duration(float64(samples)/float64(sampleRate)*float64(time.Second))
+        }
+      }
+    default:
+      if _, err := f.Seek(int64(length), 1); err != nil{
+        return tags, err
+      }
+    }
+
+    if last{
+      return tags, nil
+    }
+  }
+}
+
+/*
+  Ogg Vorbis and Opus
+*/
+
+func readOggPackets(r io.Reader, count int) ([][]byte, error){
+  var packets [][]byte
+  var packet []byte
+  header := make([]byte, 27)
+
+  for len(packets) < count{
+    if _, err := io.ReadFull(r, header); err != nil{
+      return packets, err
+    }
+    if string(header[:4]) != "OggS"{
+      return packets, fmt.Errorf("Invalid Ogg page")
+    }
+
+    segments := make([]byte, header[26])
+    if _, err := io.ReadFull(r, segments); err != nil{
+      return packets, err
+    }
+
+    for _,size := range segments{
+      data := make([]byte, size)
+      if _, err := io.ReadFull(r, data); err != nil{
+        return packets, err
+      }
+      packet = append(packet, data...)
+
+      if size < 255{
+        packets = append(packets, packet)
+        packet = nil
+      }
+    }
+  }
+  return packets, nil
+}
+
+func lastGranule(f *os.File) int64{
+  info, err := f.Stat()
+  if err != nil{
+    return 0
+  }
+
+  tailSize := int64(64*1024)
+  if tailSize > info.Size(){
+    tailSize = info.Size()
+  }
+  tail := make([]byte, tailSize)
+  if _, err := f.ReadAt(tail, info.Size()-tailSize); err != nil && err != io.EOF{
+    return 0
+  }
+
+  i := bytes.LastIndex(tail, []byte("OggS"))
+  if i < 0 || i+14 > len(tail){
+    return 0
+  }
+  return int64(binary.LittleEndian.Uint64(tail[i+6:i+14]))
+}
+
+func readOgg(f *os.File) (Tags, error){
+  tags := Tags{}
+
+  packets, err := readOggPackets(f, 2)
+  if err != nil{
+    return tags, err
+  }
+
+  ident, comment := packets[0], packets[1]
+  var sampleRate int64
+  var preSkip int64
+
+  switch{
+  case len(ident) >= 16 && string(ident[:7]) == "\x01vorbis":
+    sampleRate = int64(binary.LittleEndian.Uint32(ident[12:16]))
+    if len(comment) > 7 && string(comment[:7]) == "\x03vorbis"{
+      readVorbisComment(comment[7:], &tags)
+    }
+  case len(ident) >= 12 && string(ident[:8]) == "OpusHead":
+    // Opus granule positions are always at 48kHz
+    sampleRate = 48000
+    preSkip = int64(binary.LittleEndian.Uint16(ident[10:12]))
+    if len(comment) > 8 && string(comment[:8]) == "OpusTags"{
+      readVorbisComment(comment[8:], &tags)
+    }
+  default:
+    return tags, fmt.Errorf("Unsupported Ogg codec")
+  }
+
+  if sampleRate > 0{
+    samples := lastGranule(f) - preSkip
+    if samples > 0{
+      tags.Duration = time.Duration(float64(samples)/float64(sampleRate)*float64(time.Second))
+    }
+  }
+  return tags, nil
+}
+
+func AudioStart(path string) int64{
+  /*
+    Offset of the first audio frame, skipping any ID3v2 tag
+  */
+  f, err := os.Open(path)
+  if err != nil{
+    return 0
+  }
+  defer f.Close()
+
+  start, _ := readId3v2(f, &Tags{})
+  return start
+}
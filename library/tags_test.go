@@ -0,0 +1,389 @@
+package library
+
+import(
+  "bytes"
+  "encoding/binary"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+  "unicode/utf16"
+)
+
+/*
+  Small files are built here rather than checked in, so each header can
+  be truncated or corrupted on purpose
+*/
+
+func writeFixture(t *testing.T, dir string, name string, data []byte) string{
+  path := filepath.Join(dir, name)
+  if err := ioutil.WriteFile(path, data, 0644); err != nil{
+    t.Fatal(err)
+  }
+  return path
+}
+
+func fixtureDir(t *testing.T) (string, func()){
+  dir, err := ioutil.TempDir("", "jukebox-tags")
+  if err != nil{
+    t.Fatal(err)
+  }
+  return dir, func(){ os.RemoveAll(dir) }
+}
+
+func seconds(s float64) time.Duration{
+  return time.Duration(s*float64(time.Second))
+}
+
+func syncsafeBytes(n int) []byte{
+  return []byte{byte(n>>21)&0x7F, byte(n>>14)&0x7F, byte(n>>7)&0x7F, byte(n)&0x7F}
+}
+
+func id3Frame(version byte, id string, body []byte) []byte{
+  var b bytes.Buffer
+  b.WriteString(id)
+  switch version{
+  case 2:
+    b.Write([]byte{byte(len(body)>>16), byte(len(body)>>8), byte(len(body))})
+  case 3:
+    binary.Write(&b, binary.BigEndian, uint32(len(body)))
+    b.Write([]byte{0, 0})
+  default:
+    b.Write(syncsafeBytes(len(body)))
+    b.Write([]byte{0, 0})
+  }
+  b.Write(body)
+  return b.Bytes()
+}
+
+func latin1(text string) []byte{
+  return append([]byte{0}, text...)
+}
+
+func utf16Text(text string) []byte{
+  b := []byte{1, 0xFF, 0xFE}
+  for _, u := range utf16.Encode([]rune(text)){
+    b = append(b, byte(u), byte(u>>8))
+  }
+  return b
+}
+
+func id3Tag(version byte, frames ...[]byte) []byte{
+  body := bytes.Join(frames, nil)
+  // Padding, as most taggers leave
+  body = append(body, make([]byte, 32)...)
+  header := append([]byte{'I', 'D', '3', version, 0, 0}, syncsafeBytes(len(body))...)
+  return append(header, body...)
+}
+
+func id3v1Tag(title string, artist string, album string) []byte{
+  tag := make([]byte, 128)
+  copy(tag, "TAG")
+  copy(tag[3:33], title)
+  copy(tag[33:63], artist)
+  copy(tag[63:93], album)
+  return tag
+}
+
+// MPEG 1 layer 3, 128kbps, 44.1kHz, stereo
+var mpegHeader = []byte{0xFF, 0xFB, 0x90, 0x00}
+
+func cbrAudio(size int) []byte{
+  audio := make([]byte, size)
+  copy(audio, mpegHeader)
+  return audio
+}
+
+func xingAudio(frames uint32) []byte{
+  audio := make([]byte, 417)
+  copy(audio, mpegHeader)
+  // Stereo MPEG 1 has 32 bytes of side info after the header
+  copy(audio[36:], "Xing")
+  binary.BigEndian.PutUint32(audio[40:], 0x01)
+  binary.BigEndian.PutUint32(audio[44:], frames)
+  return audio
+}
+
+func vorbisComment(comments ...string) []byte{
+  var b bytes.Buffer
+  vendor := "jukebox tests"
+  binary.Write(&b, binary.LittleEndian, uint32(len(vendor)))
+  b.WriteString(vendor)
+  binary.Write(&b, binary.LittleEndian, uint32(len(comments)))
+  for _, c := range comments{
+    binary.Write(&b, binary.LittleEndian, uint32(len(c)))
+    b.WriteString(c)
+  }
+  return b.Bytes()
+}
+
+func flacBlock(blockType byte, last bool, body []byte) []byte{
+  if last{
+    blockType |= 0x80
+  }
+  header := []byte{blockType, byte(len(body)>>16), byte(len(body)>>8), byte(len(body))}
+  return append(header, body...)
+}
+
+func flacStreamInfo(sampleRate int, samples int64) []byte{
+  b := make([]byte, 34)
+  b[10] = byte(sampleRate>>12)
+  b[11] = byte(sampleRate>>4)
+  // Stereo, 16 bits per sample
+  b[12] = byte(sampleRate<<4) | 1<<1
+  b[13] = 15<<4 | byte(samples>>32)&0x0F
+  binary.BigEndian.PutUint32(b[14:18], uint32(samples))
+  return b
+}
+
+func oggPage(granule int64, packets ...[]byte) []byte{
+  var segments []byte
+  var data []byte
+  for _, p := range packets{
+    n := len(p)
+    for ; n >= 255; n -= 255{
+      segments = append(segments, 255)
+    }
+    segments = append(segments, byte(n))
+    data = append(data, p...)
+  }
+
+  header := make([]byte, 27)
+  copy(header, "OggS")
+  binary.LittleEndian.PutUint64(header[6:14], uint64(granule))
+  header[26] = byte(len(segments))
+  return bytes.Join([][]byte{header, segments, data}, nil)
+}
+
+func vorbisIdent(sampleRate uint32) []byte{
+  b := make([]byte, 30)
+  copy(b, "\x01vorbis")
+  b[11] = 2
+  binary.LittleEndian.PutUint32(b[12:16], sampleRate)
+  return b
+}
+
+func opusHead(preSkip uint16) []byte{
+  b := make([]byte, 19)
+  copy(b, "OpusHead")
+  b[8] = 1
+  b[9] = 2
+  binary.LittleEndian.PutUint16(b[10:12], preSkip)
+  return b
+}
+
+func TestReadTags(t *testing.T){
+  dir, cleanup := fixtureDir(t)
+  defer cleanup()
+
+  longComment := "TITLE=" + strings.Repeat("a", 300)
+
+  tests := []struct{
+    name  string
+    file  string
+    data  []byte
+    tags  Tags
+  }{
+    {
+      name: "ID3v2.3 with TLEN",
+      file: "v23.mp3",
+      data: append(id3Tag(3,
+        id3Frame(3, "TIT2", latin1("Mr. Brightside")),
+        id3Frame(3, "TPE1", latin1("The Killers")),
+        id3Frame(3, "TALB", latin1("Hot Fuss")),
+        id3Frame(3, "TLEN", latin1("222000")),
+      ), cbrAudio(1000)...),
+      tags: Tags{Title: "Mr. Brightside", Artist: "The Killers", Album: "Hot Fuss", Duration: 222*time.Second, Format: "mp3"},
+    },
+    {
+      name: "ID3v2.4 UTF-16 with CBR audio",
+      file: "v24.mp3",
+      data: append(id3Tag(4,
+        id3Frame(4, "TIT2", utf16Text("Jóga")),
+        id3Frame(4, "TPE1", utf16Text("Björk")),
+      ), cbrAudio(16000)...),
+      tags: Tags{Title: "Jóga", Artist: "Björk", Duration: time.Second, Format: "mp3"},
+    },
+    {
+      name: "ID3v2.2 with a Xing header",
+      file: "v22.mp3",
+      data: append(id3Tag(2,
+        id3Frame(2, "TT2", latin1("Old Tagger")),
+        id3Frame(2, "TP1", latin1("Someone")),
+        id3Frame(2, "TAL", latin1("Something")),
+      ), xingAudio(100)...),
+      tags: Tags{Title: "Old Tagger", Artist: "Someone", Album: "Something", Duration: seconds(100*1152/44100.0), Format: "mp3"},
+    },
+    {
+      name: "ID3v1 only",
+      file: "v1.mp3",
+      data: append(cbrAudio(16000), id3v1Tag("Title One", "Artist One", "Album One")...),
+      tags: Tags{Title: "Title One", Artist: "Artist One", Album: "Album One", Duration: time.Second, Format: "mp3"},
+    },
+    {
+      name: "No tags",
+      file: "Untagged Track.mp3",
+      data: cbrAudio(32000),
+      tags: Tags{Title: "Untagged Track", Duration: 2*time.Second, Format: "mp3"},
+    },
+    {
+      name: "ID3 frame bigger than the tag",
+      file: "oversized.mp3",
+      data: id3Tag(3,
+        id3Frame(3, "TIT2", latin1("Kept")),
+        append([]byte("TPE1"), 0x7F, 0xFF, 0xFF, 0xFF, 0, 0, 0, 'x'),
+      ),
+      tags: Tags{Title: "Kept", Format: "mp3"},
+    },
+    {
+      name: "Corrupt MPEG header",
+      file: "corrupt.mp3",
+      data: append([]byte{0xFF, 0xFF, 0xFF, 0xFF}, make([]byte, 100)...),
+      tags: Tags{Title: "corrupt", Format: "mp3"},
+    },
+    {
+      name: "FLAC",
+      file: "track.flac",
+      data: bytes.Join([][]byte{
+        []byte("fLaC"),
+        flacBlock(0, false, flacStreamInfo(44100, 441000)),
+        flacBlock(1, false, make([]byte, 64)),
+        flacBlock(4, true, vorbisComment("title=Lazy Eye", "ARTIST=Silversun Pickups", "ALBUM=Carnavas", "no separator")),
+      }, nil),
+      tags: Tags{Title: "Lazy Eye", Artist: "Silversun Pickups", Album: "Carnavas", Duration: 10*time.Second, Format: "flac"},
+    },
+    {
+      name: "FLAC with ID3 in front",
+      file: "id3.flac",
+      data: bytes.Join([][]byte{
+        id3Tag(3, id3Frame(3, "TIT2", latin1("From ID3"))),
+        []byte("fLaC"),
+        flacBlock(0, true, flacStreamInfo(48000, 96000)),
+      }, nil),
+      tags: Tags{Title: "From ID3", Duration: 2*time.Second, Format: "flac"},
+    },
+    {
+      name: "FLAC comment count past the block",
+      file: "comments.flac",
+      data: bytes.Join([][]byte{
+        []byte("fLaC"),
+        flacBlock(4, true, append(vorbisComment("TITLE=First"), 0xFF, 0xFF, 0xFF, 0x7F)),
+      }, nil),
+      tags: Tags{Title: "First", Format: "flac"},
+    },
+    {
+      name: "Ogg Vorbis",
+      file: "track.ogg",
+      data: bytes.Join([][]byte{
+        oggPage(0, vorbisIdent(44100)),
+        oggPage(0, append([]byte("\x03vorbis"), vorbisComment(longComment, "ARTIST=Long", "ALBUM=Pages")...)),
+        oggPage(44100*3, make([]byte, 100)),
+      }, nil),
+      tags: Tags{Title: longComment[6:], Artist: "Long", Album: "Pages", Duration: 3*time.Second, Format: "ogg"},
+    },
+    {
+      name: "Opus",
+      file: "track.opus",
+      data: bytes.Join([][]byte{
+        oggPage(0, opusHead(312)),
+        oggPage(0, append([]byte("OpusTags"), vorbisComment("TITLE=Opus Track", "ARTIST=Someone")...)),
+        oggPage(48000*2 + 312, make([]byte, 100)),
+      }, nil),
+      tags: Tags{Title: "Opus Track", Artist: "Someone", Duration: 2*time.Second, Format: "ogg"},
+    },
+  }
+
+  for _, test := range tests{
+    tags, err := ReadTags(writeFixture(t, dir, test.file, test.data))
+    if err != nil{
+      t.Errorf("%s: unexpected error %s", test.name, err)
+    }
+    if tags != test.tags{
+      t.Errorf("%s: got %+v, want %+v", test.name, tags, test.tags)
+    }
+  }
+}
+
+func TestReadTagsBroken(t *testing.T){
+  /*
+    Broken files are an error but still get a title from the file name, and
+    nothing should panic or read past the end
+  */
+  dir, cleanup := fixtureDir(t)
+  defer cleanup()
+
+  tagged := id3Tag(3, id3Frame(3, "TIT2", latin1("Truncated")))
+  flac := bytes.Join([][]byte{
+    []byte("fLaC"),
+    flacBlock(0, false, flacStreamInfo(44100, 441000)),
+    flacBlock(4, true, vorbisComment("TITLE=Cut Off")),
+  }, nil)
+  ogg := bytes.Join([][]byte{
+    oggPage(0, vorbisIdent(44100)),
+    oggPage(0, append([]byte("\x03vorbis"), vorbisComment("TITLE=Cut Off")...)),
+  }, nil)
+
+  tests := []struct{
+    file  string
+    data  []byte
+  }{
+    {"empty.mp3", nil},
+    {"short.mp3", []byte("ID3")},
+    {"truncated tag.mp3", tagged[:len(tagged)-10]},
+    {"huge tag.mp3", append([]byte{'I', 'D', '3', 3, 0, 0, 0x7F, 0x7F, 0x7F, 0x7F}, make([]byte, 20)...)},
+    {"empty.flac", nil},
+    {"not flac.flac", []byte("RIFF....WAVEfmt ")},
+    {"no blocks.flac", []byte("fLaC")},
+    {"truncated header.flac", flac[:6]},
+    {"truncated block.flac", flac[:20]},
+    {"truncated comment.flac", flac[:len(flac)-5]},
+    {"empty.ogg", nil},
+    {"not ogg.ogg", []byte("OggX" + strings.Repeat("\x00", 40))},
+    {"truncated page.ogg", ogg[:20]},
+    {"truncated segments.ogg", ogg[:27]},
+    {"truncated packet.ogg", ogg[:len(ogg)-5]},
+    {"one packet.ogg", oggPage(0, vorbisIdent(44100))},
+    {"unknown codec.ogg", bytes.Join([][]byte{
+      oggPage(0, []byte("\x80theora")),
+      oggPage(0, []byte("\x81theora")),
+    }, nil)},
+  }
+
+  for _, test := range tests{
+    path := writeFixture(t, dir, test.file, test.data)
+    tags, err := ReadTags(path)
+    if err == nil{
+      t.Errorf("%s: read without an error", test.file)
+    }
+    if want := strings.TrimSuffix(test.file, filepath.Ext(test.file)); tags.Title != want{
+      t.Errorf("%s: title %q, want %q", test.file, tags.Title, want)
+    }
+  }
+}
+
+func TestReadTagsUnsupported(t *testing.T){
+  dir, cleanup := fixtureDir(t)
+  defer cleanup()
+
+  if _, err := ReadTags(writeFixture(t, dir, "track.wav", []byte("RIFF"))); err == nil{
+    t.Error("Read tags from a WAV file")
+  }
+  if _, err := ReadTags(filepath.Join(dir, "missing.mp3")); err == nil{
+    t.Error("Read tags from a missing file")
+  }
+}
+
+func TestAudioStart(t *testing.T){
+  dir, cleanup := fixtureDir(t)
+  defer cleanup()
+
+  tag := id3Tag(4, id3Frame(4, "TIT2", latin1("Title")))
+  if start := AudioStart(writeFixture(t, dir, "tagged.mp3", append(tag, cbrAudio(1000)...))); start != int64(len(tag)){
+    t.Errorf("AudioStart = %d, want %d", start, len(tag))
+  }
+  if start := AudioStart(writeFixture(t, dir, "untagged.mp3", cbrAudio(1000))); start != 0{
+    t.Errorf("AudioStart = %d for an untagged file", start)
+  }
+}
@@ -0,0 +1,46 @@
+package models
+
+import(
+  "github.com/samarudge/jukebox/db"
+)
+
+const(
+  AutoDJOff             = ""
+  AutoDJPlaylist        = "playlist"
+  AutoDJHistory         = "history"
+  AutoDJRecommendations = "recommendations"
+)
+
+func ValidAutoDJ(mode string) bool{
+  switch mode{
+  case AutoDJOff, AutoDJPlaylist, AutoDJHistory, AutoDJRecommendations:
+    return true
+  }
+  return false
+}
+
+func (r Room) AutoDJEnabled() bool{
+  return r.AutoDJ != AutoDJOff
+}
+
+func (r Room) AutoDJFromPlaylist() bool{
+  return r.AutoDJ == AutoDJPlaylist
+}
+
+func (r Room) AutoDJFromHistory() bool{
+  return r.AutoDJ == AutoDJHistory
+}
+
+func (r Room) AutoDJFromRecommendations() bool{
+  return r.AutoDJ == AutoDJRecommendations
+}
+
+func (r Room) PlayedEntries(limit int) []QueueEntry{
+  /*
+    Tracks that played through to the end, most recent first
+  */
+  d := db.Db()
+  var entries []QueueEntry
+  d.Where("room_id = ? and status = ?", r.ID, QueueStatusPlayed).Order("finished_at desc").Limit(limit).Find(&entries)
+  return entries
+}
@@ -0,0 +1,149 @@
+package models
+
+import(
+  "github.com/samarudge/jukebox/events"
+  "strconv"
+)
+
+const(
+  EventQueue      = "queue"
+  EventNowPlaying = "nowPlaying"
+  EventVote       = "vote"
+  EventSkipVote   = "skipVote"
+  EventMembership = "membership"
+)
+
+const(
+  MembershipJoined  = "joined"
+  MembershipLeft    = "left"
+  MembershipRole    = "role"
+)
+
+type EntryData struct{
+  ID          uint    `json:"id"`
+  TrackUri    string  `json:"trackUri"`
+  Title       string  `json:"title"`
+  Artist      string  `json:"artist"`
+  DurationMs  int     `json:"durationMs"`
+  Explicit    bool    `json:"explicit"`
+  AutoAdded   bool    `json:"autoAdded"`
+  AddedBy     string  `json:"addedBy"`
+  AddedByID   uint64  `json:"addedById"`
+  Score       int     `json:"score"`
+  Status      string  `json:"status"`
+}
+
+type VoteData struct{
+  EntryID uint  `json:"entryId"`
+  Score   int   `json:"score"`
+}
+
+type SkipVoteData struct{
+  EntryID uint  `json:"entryId"`
+  Votes   int   `json:"votes"`
+  Needed  int   `json:"needed"`
+}
+
+type MemberData struct{
+  UserID  uint    `json:"userId"`
+  Name    string  `json:"name"`
+  Action  string  `json:"action"`
+  Role    string  `json:"role"`
+}
+
+func (e QueueEntry) EventData() EntryData{
+  return EntryData{
+    ID: e.ID,
+    TrackUri: e.TrackUri,
+    Title: e.Title,
+    Artist: e.Artist,
+    DurationMs: e.DurationMs,
+    Explicit: e.Explicit,
+    AutoAdded: e.AutoAdded,
+    AddedBy: e.AddedByName(),
+    AddedByID: e.AddedByID,
+    Score: e.Score,
+    Status: e.Status,
+  }
+}
+
+func (r Room) QueueData() []EntryData{
+  entries := []EntryData{}
+  for _, e := range r.ProjectedQueue(){
+    entries = append(entries, e.EventData())
+  }
+  return entries
+}
+
+func (r Room) NowPlayingData() *EntryData{
+  current, playing := r.NowPlaying()
+  if !playing{
+    return nil
+  }
+  data := current.EventData()
+  return &data
+}
+
+func (r Room) Snapshot() map[string]interface{}{
+  /*
+    Everything a client needs when it first connects, events after this
+    are changes to it
+  */
+  var members []MemberData
+  for _, u := range r.MemberList(){
+    members = append(members, MemberData{
+      UserID: u.ID,
+      Name: u.Auth().Name,
+      Role: r.RoleOf(u),
+    })
+  }
+
+  return map[string]interface{}{
+    "queue": r.QueueData(),
+    "nowPlaying": r.NowPlayingData(),
+    "members": members,
+  }
+}
+
+// Publishing is skipped when nobody is listening so it costs nothing otherwise
+
+func (r Room) PublishQueue(){
+  if events.Watched(r.ID){
+    events.Publish(r.ID, EventQueue, r.QueueData())
+  }
+}
+
+func (r Room) PublishNowPlaying(){
+  if events.Watched(r.ID){
+    events.Publish(r.ID, EventNowPlaying, r.NowPlayingData())
+  }
+}
+
+func (r Room) PublishMembership(u User, action string){
+  if events.Watched(r.ID){
+    events.Publish(r.ID, EventMembership, MemberData{
+      UserID: u.ID,
+      Name: u.Auth().Name,
+      Action: action,
+      Role: r.RoleOf(u),
+    })
+  }
+}
+
+func (e QueueEntry) publishVote(){
+  if !events.Watched(uint(e.RoomID)){
+    return
+  }
+
+  events.Publish(uint(e.RoomID), EventVote, VoteData{
+    EntryID: e.ID,
+    Score: e.Score,
+  })
+  e.room().PublishQueue()
+}
+
+func (e QueueEntry) room() Room{
+  r := Room{}
+  r.ById(strconv.FormatUint(e.RoomID, 10))
+  return r
+}
@@ -0,0 +1,140 @@
+package models
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "strings"
+  "time"
+)
+
+type PlaylistExport struct{
+  gorm.Model
+  RoomID      uint64
+  SpotifyID   uint64
+  PlaylistId  string
+  PlaylistUri string
+  Name        string
+  From        time.Time
+  To          time.Time
+  Tracks      int
+  ExportedBy    User
+  ExportedByID  uint64
+}
+
+func (r Room) PlayedBetween(from time.Time, to time.Time) []QueueEntry{
+  /*
+    Everything that played through in the range, oldest first
+  */
+  d := db.Db()
+  var entries []QueueEntry
+  d.Where("room_id = ? and status = ? and started_at >= ? and started_at < ?", r.ID, QueueStatusPlayed, from, to).Order("started_at").Find(&entries)
+  return entries
+}
+
+func (r Room) ExportUris(from time.Time, to time.Time) []string{
+  // Playlists can only hold Spotify tracks, each track is only added once
+  seen := make(map[string]bool)
+  var uris []string
+  for _, e := range r.PlayedBetween(from, to){
+    if !strings.HasPrefix(e.TrackUri, "spotify:track:") || seen[e.TrackUri]{
+      continue
+    }
+    seen[e.TrackUri] = true
+    uris = append(uris, e.TrackUri)
+  }
+  return uris
+}
+
+func (r Room) FindExport(s Spotify, name string) (PlaylistExport, bool){
+  d := db.Db()
+  e := PlaylistExport{}
+  d.Where("room_id = ? and spotify_id = ? and name = ?", r.ID, s.ID, name).First(&e)
+  return e, !d.NewRecord(e)
+}
+
+func (r Room) Exports() []PlaylistExport{
+  d := db.Db()
+  var exports []PlaylistExport
+  d.Where("room_id = ?", r.ID).Order("updated_at desc").Find(&exports)
+  return exports
+}
+
+func (e PlaylistExport) Exporter() User{
+  d := db.Db()
+  u := User{}
+  d.Where("id = ?", e.ExportedByID).First(&u)
+  return u
+}
+
+func (e PlaylistExport) Range() string{
+  return e.From.Format("Jan 2 2006") + " - " + e.To.Add(-time.Second).Format("Jan 2 2006")
+}
+
+func (e PlaylistExport) ExportedStamp() string{
+  return e.UpdatedAt.Format("Mon Jan 2 15:04")
+}
+
+func (e PlaylistExport) Link() string{
+  return "https://open.spotify.com/playlist/" + e.PlaylistId
+}
+
+func (e PlaylistExport) SystemAccount() bool{
+  s := Spotify{}
+  s.LoadSystem()
+  return s.Exists() && uint64(s.ID) == e.SpotifyID
+}
+
+func (r Room) Export(s Spotify, u User, name string, from time.Time, to time.Time) (PlaylistExport, error){
+  /*
+    Exporting again with the same name on the same account replaces the
+    playlist's tracks rather than making another one
+  */
+  d := db.Db()
+
+  uris := r.ExportUris(from, to)
+  if len(uris) == 0{
+    return PlaylistExport{}, fmt.Errorf("Nothing from Spotify played in %s between those dates", r.Name)
+  }
+
+  client := s.Client()
+  e, exists := r.FindExport(s, name)
+  if !exists{
+    me, err := client.Me()
+    if err != nil{
+      return e, err
+    }
+
+    description := fmt.Sprintf("What played in %s from %s to %s", r.Name, from.Format("Jan 2 2006"), to.Add(-time.Second).Format("Jan 2 2006"))
+    playlist, err := client.CreatePlaylist(me.Id, name, description)
+    if err != nil{
+      return e, err
+    }
+
+    e.RoomID = uint64(r.ID)
+    e.SpotifyID = uint64(s.ID)
+    e.PlaylistId = playlist.Id
+    e.PlaylistUri = playlist.Uri
+    e.Name = name
+  }
+
+  err := client.ReplacePlaylistTracks(e.PlaylistId, uris)
+  if err != nil{
+    return e, err
+  }
+
+  e.From = from
+  e.To = to
+  e.Tracks = len(uris)
+  e.ExportedByID = uint64(u.ID)
+  d.Save(&e)
+
+  log.WithFields(log.Fields{
+    "roomId": r.ID,
+    "spotifyId": s.ID,
+    "playlist": e.PlaylistId,
+    "tracks": e.Tracks,
+  }).Info("Exported room history")
+  return e, nil
+}
@@ -0,0 +1,68 @@
+package models
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  "fmt"
+  "strings"
+  "time"
+)
+
+const LocalUriPrefix = "local:track:"
+
+type LibraryTrack struct{
+  gorm.Model
+  Path        string
+  Title       string
+  Artist      string
+  Album       string
+  DurationMs  int
+  Format      string
+  ModTime     time.Time
+}
+
+func (t *LibraryTrack) ByPath(path string){
+  d := db.Db()
+  d.Where("path = ?", path).First(&t)
+}
+
+func (t *LibraryTrack) ByUri(uri string) bool{
+  if !strings.HasPrefix(uri, LocalUriPrefix){
+    return false
+  }
+
+  d := db.Db()
+  d.Where("id = ?", strings.TrimPrefix(uri, LocalUriPrefix)).First(&t)
+  return !d.NewRecord(t)
+}
+
+func (t LibraryTrack) Uri() string{
+  return fmt.Sprintf("%s%d", LocalUriPrefix, t.ID)
+}
+
+func (t LibraryTrack) Duration() string{
+  return fmt.Sprintf("%d:%02d", t.DurationMs/60000, (t.DurationMs/1000)%60)
+}
+
+func SearchLibrary(query string, limit int, offset int) ([]LibraryTrack, int){
+  d := db.Db()
+  var tracks []LibraryTrack
+  total := 0
+
+  like := fmt.Sprintf("%%%s%%", query)
+  q := d.Model(&LibraryTrack{}).Where("title like ? or artist like ? or album like ?", like, like, like)
+  q.Count(&total)
+  q.Order("artist, album, title").Limit(limit).Offset(offset).Find(&tracks)
+  return tracks, total
+}
+
+func LibraryTrackCount() int{
+  d := db.Db()
+  count := 0
+  d.Model(&LibraryTrack{}).Count(&count)
+  return count
+}
+
+func (e QueueEntry) IsLocal() bool{
+  return strings.HasPrefix(e.TrackUri, LocalUriPrefix)
+}
@@ -0,0 +1,126 @@
+package models
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/events"
+  log "github.com/Sirupsen/logrus"
+  "strconv"
+  "time"
+)
+
+type RoomMembership struct{
+  gorm.Model
+  RoomID    uint64
+  UserID    uint64
+  Active    bool
+  JoinedAt  time.Time
+  LeftAt    time.Time
+}
+
+func (m RoomMembership) Room() Room{
+  r := Room{}
+  r.ById(strconv.FormatUint(m.RoomID, 10))
+  return r
+}
+
+func (m RoomMembership) User() User{
+  u := User{}
+  u.ById(strconv.FormatUint(m.UserID, 10))
+  return u
+}
+
+func (m RoomMembership) JoinedStamp() string{
+  return m.JoinedAt.Format("Mon Jan 2 2006 15:04")
+}
+
+func (m RoomMembership) LeftStamp() string{
+  if m.Active{
+    return ""
+  }
+  return m.LeftAt.Format("Mon Jan 2 2006 15:04")
+}
+
+func (r Room) MembershipHistory(limit int) []RoomMembership{
+  d := db.Db()
+  var memberships []RoomMembership
+  d.Where("room_id = ?", r.ID).Order("joined_at desc").Limit(limit).Find(&memberships)
+  return memberships
+}
+
+func (u User) RoomHistory(limit int) []RoomMembership{
+  d := db.Db()
+  var memberships []RoomMembership
+  d.Where("user_id = ?", u.ID).Order("joined_at desc").Limit(limit).Find(&memberships)
+  return memberships
+}
+
+func (u User) CurrentRoom() (Room, bool){
+  d := db.Db()
+  r := Room{}
+  if u.RoomID == 0{
+    return r, false
+  }
+  r.ById(strconv.FormatUint(u.RoomID, 10))
+  return r, !d.NewRecord(r)
+}
+
+func (r *Room) JoinUser(u User) error{
+  /*
+    Users are only ever in one room, so joining leaves the previous one
+  */
+  d := db.Db()
+
+  if r.HasMember(u){
+    return nil
+  }
+
+  if previous, inRoom := u.CurrentRoom(); inRoom{
+    previous.RemoveUser(u)
+  }
+
+  t := d.Begin()
+  t.Model(&u).UpdateColumn("room_id", r.ID)
+  t.Create(&RoomMembership{
+    RoomID: uint64(r.ID),
+    UserID: uint64(u.ID),
+    Active: true,
+    JoinedAt: time.Now().UTC(),
+  })
+  err := t.Commit().Error
+  if err != nil{
+    return err
+  }
+
+  log.WithFields(log.Fields{
+    "roomId": r.ID,
+    "userId": u.ID,
+  }).Debug("Joined room")
+  r.PublishMembership(u, MembershipJoined)
+  r.fireWebhooks(WebhookMemberJoined, MemberData{
+    UserID: u.ID,
+    Name: u.Auth().Name,
+    Action: MembershipJoined,
+    Role: r.RoleOf(u),
+  })
+  return nil
+}
+
+func (r *Room) RemoveUser(u User){
+  d := db.Db()
+
+  t := d.Begin()
+  t.Model(&User{}).Where("id = ? and room_id = ?", u.ID, r.ID).UpdateColumn("room_id", 0)
+  t.Model(&RoomMembership{}).Where("room_id = ? and user_id = ? and active = ?", r.ID, u.ID, true).Updates(map[string]interface{}{
+    "active": false,
+    "left_at": time.Now().UTC(),
+  })
+  t.Commit()
+
+  log.WithFields(log.Fields{
+    "roomId": r.ID,
+    "userId": u.ID,
+  }).Debug("Left room")
+  r.PublishMembership(u, MembershipLeft)
+  events.CloseUser(r.ID, u.ID)
+}
@@ -0,0 +1,31 @@
+package models
+
+import(
+  "github.com/samarudge/jukebox/db"
+)
+
+// Creates and updates the tables, run on start and by tests with a fresh database
+func Migrate(){
+  d := db.Db()
+  d.AutoMigrate(&User{})
+  d.AutoMigrate(&Oauth2{})
+  d.AutoMigrate(&Room{})
+  d.AutoMigrate(&Spotify{})
+  d.AutoMigrate(&QueueEntry{})
+  d.AutoMigrate(&Vote{})
+  d.Model(&Vote{}).AddUniqueIndex("idx_vote_entry_user", "queue_entry_id", "user_id")
+  d.AutoMigrate(&SkipVote{})
+  d.Model(&SkipVote{}).AddUniqueIndex("idx_skip_vote_entry_user", "queue_entry_id", "user_id")
+  d.AutoMigrate(&SkipEvent{})
+  d.AutoMigrate(&LibraryTrack{})
+  d.AutoMigrate(&RoomMembership{})
+  d.AutoMigrate(&RoomRole{})
+  d.AutoMigrate(&PlaylistExport{})
+  d.AutoMigrate(&Play{})
+  d.AutoMigrate(&ApiToken{})
+  d.AutoMigrate(&Webhook{})
+  d.AutoMigrate(&WebhookDelivery{})
+  d.Model(&ApiToken{}).AddUniqueIndex("idx_api_token_hash", "hash")
+  d.Model(&RoomRole{}).AddUniqueIndex("idx_room_role_room_user", "room_id", "user_id")
+  d.Model(&LibraryTrack{}).AddUniqueIndex("idx_library_track_path", "path")
+}
@@ -8,6 +8,7 @@ import(
   "github.com/samarudge/jukebox/db"
   log "github.com/Sirupsen/logrus"
   "fmt"
+  "strings"
 )
 
 type Oauth2 struct{
@@ -20,6 +21,8 @@ type Oauth2 struct{
   AuthValid     bool
   LastAuth      time.Time
   TokenExpires  time.Time
+  // Space separated, as granted by the provider
+  Scopes        string
 }
 
 func (a *Oauth2) LoadProvider() auth.OauthProvider{
@@ -90,6 +93,9 @@ func (a *Oauth2) EnsureAuth(token *oauth2.Token) (auth.UserData, error){
   a.RefreshToken = token.RefreshToken
   a.TokenExpires = token.Expiry.UTC()
   a.LastAuth = time.Now().UTC()
+  if scopes, hasScopes := token.Extra("scope").(string); hasScopes && scopes != ""{
+    a.Scopes = scopes
+  }
 
   if d.NewRecord(a){
     a.Provider = provider.ProviderSlug()
@@ -99,6 +105,20 @@ func (a *Oauth2) EnsureAuth(token *oauth2.Token) (auth.UserData, error){
   return userData, nil
 }
 
+func (a Oauth2) HasScopes(scopes []string) bool{
+  granted := make(map[string]bool)
+  for _, scope := range strings.Fields(a.Scopes){
+    granted[scope] = true
+  }
+
+  for _, scope := range scopes{
+    if !granted[scope]{
+      return false
+    }
+  }
+  return true
+}
+
 func (a *Oauth2) CreateToken() *oauth2.Token{
   t := oauth2.Token{}
 
@@ -0,0 +1,153 @@
+package models
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  "fmt"
+  "strconv"
+  "time"
+)
+
+// How many rows to show in each statistics table
+const statsLimit = 10
+
+type Play struct{
+  gorm.Model
+  RoomID          uint64
+  QueueEntryID    uint64
+  TrackUri        string
+  Title           string
+  Artist          string
+  RequestedByID   uint64
+  AutoAdded       bool
+  StartedAt       time.Time
+  PlayedMs        int
+  Skipped         bool
+}
+
+func RecordPlay(e QueueEntry){
+  /*
+    Called once a queue entry has finished, played time is capped at the
+    track's length in case the player was slow to notice it ended
+  */
+  d := db.Db()
+  playedMs := int(e.FinishedAt.Sub(e.StartedAt)/time.Millisecond)
+  if e.DurationMs > 0 && playedMs > e.DurationMs{
+    playedMs = e.DurationMs
+  }
+  if playedMs < 0{
+    playedMs = 0
+  }
+
+  p := Play{
+    RoomID: e.RoomID,
+    QueueEntryID: uint64(e.ID),
+    TrackUri: e.TrackUri,
+    Title: e.Title,
+    Artist: e.Artist,
+    RequestedByID: e.AddedByID,
+    AutoAdded: e.AutoAdded,
+    StartedAt: e.StartedAt,
+    PlayedMs: playedMs,
+    Skipped: e.Status == QueueStatusSkipped,
+  }
+  d.Create(&p)
+}
+
+type TrackPlays struct{
+  TrackUri  string
+  Title     string
+  Artist    string
+  Plays     int
+}
+
+type ArtistPlays struct{
+  Artist  string
+  Plays   int
+}
+
+type RequesterPlays struct{
+  RequestedByID uint64
+  Plays         int
+}
+
+func (p RequesterPlays) User() User{
+  u := User{}
+  u.ById(strconv.FormatUint(p.RequestedByID, 10))
+  return u
+}
+
+type RoomPlays struct{
+  RoomID  uint64
+  Plays   int
+}
+
+func (p RoomPlays) Room() Room{
+  r := Room{}
+  r.ById(strconv.FormatUint(p.RoomID, 10))
+  return r
+}
+
+type PlayStats struct{
+  column  string
+  id      uint
+}
+
+func RoomStats(r Room) PlayStats{
+  return PlayStats{column: "room_id", id: r.ID}
+}
+
+func UserStats(u User) PlayStats{
+  // A user's stats are about the tracks they asked for
+  return PlayStats{column: "requested_by_id", id: u.ID}
+}
+
+func (s PlayStats) plays() *gorm.DB{
+  d := db.Db()
+  return d.Table("plays").Where(fmt.Sprintf("deleted_at is null and %s = ?", s.column), s.id)
+}
+
+func (s PlayStats) TotalPlays() int{
+  var count int
+  s.plays().Count(&count)
+  return count
+}
+
+func (s PlayStats) ListeningHours() string{
+  var total struct{
+    PlayedMs  int64
+  }
+  s.plays().Select("sum(played_ms) as played_ms").Scan(&total)
+  return fmt.Sprintf("%.1f", float64(total.PlayedMs)/float64(time.Hour/time.Millisecond))
+}
+
+func (s PlayStats) TopTracks() []TrackPlays{
+  var tracks []TrackPlays
+  s.plays().Select("track_uri, title, artist, count(*) as plays").Where("skipped = ?", false).Group("track_uri, title, artist").Order("plays desc").Limit(statsLimit).Scan(&tracks)
+  return tracks
+}
+
+func (s PlayStats) MostSkipped() []TrackPlays{
+  var tracks []TrackPlays
+  s.plays().Select("track_uri, title, artist, count(*) as plays").Where("skipped = ?", true).Group("track_uri, title, artist").Order("plays desc").Limit(statsLimit).Scan(&tracks)
+  return tracks
+}
+
+func (s PlayStats) TopArtists() []ArtistPlays{
+  var artists []ArtistPlays
+  s.plays().Select("artist, count(*) as plays").Where("skipped = ?", false).Group("artist").Order("plays desc").Limit(statsLimit).Scan(&artists)
+  return artists
+}
+
+func (s PlayStats) TopRequesters() []RequesterPlays{
+  // The auto-DJ isn't anybody
+  var requesters []RequesterPlays
+  s.plays().Select("requested_by_id, count(*) as plays").Where("auto_added = ?", false).Group("requested_by_id").Order("plays desc").Limit(statsLimit).Scan(&requesters)
+  return requesters
+}
+
+func (s PlayStats) TopRooms() []RoomPlays{
+  var rooms []RoomPlays
+  s.plays().Select("room_id, count(*) as plays").Group("room_id").Order("plays desc").Limit(statsLimit).Scan(&rooms)
+  return rooms
+}
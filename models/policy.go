@@ -0,0 +1,60 @@
+package models
+
+import(
+  "github.com/samarudge/jukebox/db"
+  "fmt"
+  "time"
+)
+
+/*
+  Room policies are checked when a track is queued, a zero limit means the
+  policy is turned off
+*/
+
+func (r Room) MaxTrackMinutes() int{
+  return r.MaxTrackSeconds/60
+}
+
+func (r Room) QueuedBy(u User) int{
+  d := db.Db()
+  var count int
+  d.Model(&QueueEntry{}).Where("room_id = ? and added_by_id = ? and status = ?", r.ID, u.ID, QueueStatusQueued).Count(&count)
+  return count
+}
+
+func (r Room) PlayedSince(trackUri string, since time.Time) bool{
+  d := db.Db()
+  var count int
+  d.Model(&QueueEntry{}).Where("room_id = ? and track_uri = ? and status in (?) and started_at > ?", r.ID, trackUri, []string{QueueStatusPlaying, QueueStatusPlayed, QueueStatusSkipped}, since).Count(&count)
+  return count > 0
+}
+
+func (r Room) CheckPolicy(u User, e QueueEntry) error{
+  if r.MaxQueuedPerUser > 0 && r.QueuedBy(u) >= r.MaxQueuedPerUser{
+    return fmt.Errorf("You already have %d tracks queued in this room, wait for some to play before adding more", r.MaxQueuedPerUser)
+  }
+
+  return r.CheckTrackPolicy(e)
+}
+
+func (r Room) CheckTrackPolicy(e QueueEntry) error{
+  /*
+    The rules that apply to a track no matter who queued it
+  */
+  if r.MaxTrackSeconds > 0 && e.DurationMs > r.MaxTrackSeconds*1000{
+    return fmt.Errorf("%s is too long, tracks in this room can be at most %d:%02d", e.Title, r.MaxTrackSeconds/60, r.MaxTrackSeconds%60)
+  }
+
+  if r.BlockExplicit && e.Explicit{
+    return fmt.Errorf("%s is marked explicit and this room doesn't allow explicit tracks", e.Title)
+  }
+
+  if r.RepeatWindowHours > 0{
+    since := time.Now().UTC().Add(-time.Hour*time.Duration(r.RepeatWindowHours))
+    if r.PlayedSince(e.TrackUri, since){
+      return fmt.Errorf("%s has been played in the last %d hours, try something else", e.Title, r.RepeatWindowHours)
+    }
+  }
+
+  return nil
+}
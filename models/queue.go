@@ -0,0 +1,247 @@
+package models
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "time"
+)
+
+const(
+  QueueStatusQueued     = "queued"
+  QueueStatusPlaying    = "playing"
+  QueueStatusPlayed     = "played"
+  QueueStatusSkipped    = "skipped"
+  // The room's player couldn't play it, so it never counts as played or skipped
+  QueueStatusUnplayable = "unplayable"
+)
+
+type QueueEntry struct{
+  gorm.Model
+  RoomID      uint64
+  TrackUri    string
+  Title       string
+  Artist      string
+  DurationMs  int
+  Explicit    bool
+  AutoAdded   bool
+  AddedBy     User
+  AddedByID   uint64
+  AddedAt     time.Time
+  Position    int
+  Score       int
+  Status      string  `sql:"default:'queued'"`
+  StartedAt   time.Time
+  FinishedAt  time.Time
+}
+
+func (e *QueueEntry) ById(entryId string){
+  d := db.Db()
+  d.Where("id = ?", entryId).First(&e)
+}
+
+func (e QueueEntry) Adder() User{
+  d := db.Db()
+  u := User{}
+  d.Where("id = ?", e.AddedByID).First(&u)
+  return u
+}
+
+func (e QueueEntry) AddedByName() string{
+  if e.AutoAdded{
+    return "Auto-DJ"
+  }
+  return e.Adder().Auth().Name
+}
+
+func (e QueueEntry) Duration() string{
+  return fmt.Sprintf("%d:%02d", e.DurationMs/60000, (e.DurationMs/1000)%60)
+}
+
+func (e QueueEntry) AddedStamp() string{
+  return e.AddedAt.Format("Mon Jan 2 15:04")
+}
+
+func (e QueueEntry) CanRemove(u User, r Room) bool{
+  return uint64(u.ID) == e.AddedByID || r.CanModerate(u)
+}
+
+func (e QueueEntry) Url() string{
+  return fmt.Sprintf("/rooms/%d/queue/%d", e.RoomID, e.ID)
+}
+
+func (e *QueueEntry) Remove(){
+  d := db.Db()
+  d.Delete(&e)
+  log.WithFields(log.Fields{
+    "entryId": e.ID,
+    "roomId": e.RoomID,
+  }).Debug("Removed queue entry")
+  e.room().PublishQueue()
+}
+
+func (r Room) Queue() []QueueEntry{
+  /*
+    Highest voted first, ties go to whoever queued first. Anything the
+    auto-DJ added always waits behind people's picks
+  */
+  d := db.Db()
+  var entries []QueueEntry
+  d.Where("room_id = ? and status = ?", r.ID, QueueStatusQueued).Order("auto_added, score desc, position, added_at").Find(&entries)
+  return entries
+}
+
+func (r Room) NextEntry() (QueueEntry, bool){
+  queue := r.ProjectedQueue()
+  if len(queue) == 0{
+    return QueueEntry{}, false
+  }
+  return queue[0], true
+}
+
+func (r *Room) Enqueue(u User, e *QueueEntry){
+  d := db.Db()
+
+  last := QueueEntry{}
+  d.Where("room_id = ? and status = ?", r.ID, QueueStatusQueued).Order("position desc").First(&last)
+
+  e.Model = gorm.Model{}
+  e.RoomID = uint64(r.ID)
+  e.AddedByID = uint64(u.ID)
+  e.AddedAt = time.Now().UTC()
+  e.Position = last.Position + 1
+  e.Status = QueueStatusQueued
+
+  d.Create(&e)
+  log.WithFields(log.Fields{
+    "entryId": e.ID,
+    "roomId": r.ID,
+    "track": e.TrackUri,
+  }).Debug("Queued track")
+  r.PublishQueue()
+  r.fireWebhooks(WebhookTrackQueued, e.EventData())
+}
+
+func (r *Room) MoveEntry(e QueueEntry, up bool){
+  /*
+    Swap the entry with its neighbour in the queue
+  */
+  d := db.Db()
+
+  queue := r.Queue()
+  for i := range queue{
+    if queue[i].ID != e.ID{
+      continue
+    }
+
+    j := i + 1
+    if up{
+      j = i - 1
+    }
+    if j < 0 || j >= len(queue){
+      return
+    }
+
+    if queue[i].Score != queue[j].Score{
+      // Votes take priority over manual ordering
+      return
+    }
+    queue[i], queue[j] = queue[j], queue[i]
+
+    /*
+      Renumber the whole queue rather than swapping the two positions,
+      enqueues landing together can read the same last position and share it
+    */
+    t := d.Begin()
+    for k := range queue{
+      if queue[k].Position != k{
+        t.Model(&queue[k]).UpdateColumn("position", k)
+      }
+    }
+    t.Commit()
+    r.PublishQueue()
+    return
+  }
+}
+
+func (r Room) History(limit int) []QueueEntry{
+  d := db.Db()
+  var entries []QueueEntry
+  d.Where("room_id = ? and status in (?)", r.ID, []string{QueueStatusPlayed, QueueStatusSkipped}).Order("finished_at desc").Limit(limit).Find(&entries)
+  return entries
+}
+
+func (e QueueEntry) Skipped() bool{
+  return e.Status == QueueStatusSkipped
+}
+
+func (e QueueEntry) FinishedStamp() string{
+  return e.FinishedAt.Format("Mon Jan 2 15:04")
+}
+
+func (r Room) NowPlaying() (QueueEntry, bool){
+  d := db.Db()
+  e := QueueEntry{}
+  d.Where("room_id = ? and status = ?", r.ID, QueueStatusPlaying).First(&e)
+  return e, !d.NewRecord(e)
+}
+
+func (r *Room) Advance(skipped bool) (QueueEntry, bool){
+  /*
+    Finish whatever is playing and start the next track in the queue
+  */
+  finished := QueueStatusPlayed
+  if skipped{
+    finished = QueueStatusSkipped
+  }
+
+  lock := skipLock(r.ID)
+  lock.Lock()
+  defer lock.Unlock()
+  return r.advance(finished)
+}
+
+func (r *Room) AdvanceUnplayable() (QueueEntry, bool){
+  lock := skipLock(r.ID)
+  lock.Lock()
+  defer lock.Unlock()
+  return r.advance(QueueStatusUnplayable)
+}
+
+func (r *Room) advance(finished string) (QueueEntry, bool){
+  // Callers hold the room's skip lock
+  d := db.Db()
+
+  current, playing := r.NowPlaying()
+  if playing{
+    current.Status = finished
+    current.FinishedAt = time.Now().UTC()
+    d.Save(&current)
+    if finished != QueueStatusUnplayable{
+      RecordPlay(current)
+    }
+  }
+
+  next, found := r.NextEntry()
+  if !found{
+    if playing{
+      r.PublishNowPlaying()
+    }
+    return next, false
+  }
+
+  next.Status = QueueStatusPlaying
+  next.StartedAt = time.Now().UTC()
+  d.Save(&next)
+
+  log.WithFields(log.Fields{
+    "roomId": r.ID,
+    "entryId": next.ID,
+    "track": next.TrackUri,
+  }).Debug("Now playing")
+  r.PublishNowPlaying()
+  r.PublishQueue()
+  r.fireWebhooks(WebhookTrackStarted, next.EventData())
+  return next, true
+}
@@ -0,0 +1,44 @@
+package models_test
+
+import(
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/testdb"
+  "reflect"
+  "testing"
+)
+
+func queueUris(r models.Room) []string{
+  var uris []string
+  for _, e := range r.Queue(){
+    uris = append(uris, e.TrackUri)
+  }
+  return uris
+}
+
+func TestMoveEntrySharedPosition(t *testing.T){
+  defer testdb.Open(t)()
+  d := db.Db()
+
+  u := testdb.User(t, models.Oauth2{Provider: "google"})
+  room := testdb.Room(t, u, "Office", "spotify:track:first", "spotify:track:second", "spotify:track:third")
+
+  // Two enqueues that landed together and read the same last position
+  queue := room.Queue()
+  if len(queue) != 3{
+    t.Fatalf("%d entries queued, want 3", len(queue))
+  }
+  d.Model(&queue[2]).UpdateColumn("position", queue[1].Position)
+
+  room.MoveEntry(queue[2], true)
+  want := []string{"spotify:track:first", "spotify:track:third", "spotify:track:second"}
+  if uris := queueUris(room); !reflect.DeepEqual(uris, want){
+    t.Fatalf("Queue is %q after moving the third track up", uris)
+  }
+
+  room.MoveEntry(queue[2], true)
+  want = []string{"spotify:track:third", "spotify:track:first", "spotify:track:second"}
+  if uris := queueUris(room); !reflect.DeepEqual(uris, want){
+    t.Errorf("Queue is %q after moving the third track up again", uris)
+  }
+}
@@ -0,0 +1,113 @@
+package models
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  log "github.com/Sirupsen/logrus"
+)
+
+const(
+  RoleOwner     = "owner"
+  RoleDJ        = "dj"
+  RoleListener  = "listener"
+)
+
+var Roles = []string{RoleOwner, RoleDJ, RoleListener}
+
+type RoomRole struct{
+  gorm.Model
+  RoomID  uint64
+  UserID  uint64
+  Role    string
+}
+
+func ValidRole(role string) bool{
+  for _,r := range Roles{
+    if r == role{
+      return true
+    }
+  }
+  return false
+}
+
+func (r Room) RoleOf(u User) string{
+  if u.ID == 0{
+    return ""
+  }
+
+  // The creator can never be locked out of their own room
+  if uint64(u.ID) == r.CreatorID{
+    return RoleOwner
+  }
+
+  d := db.Db()
+  role := RoomRole{}
+  d.Where("room_id = ? and user_id = ?", r.ID, u.ID).First(&role)
+  if d.NewRecord(role){
+    return RoleListener
+  }
+  return role.Role
+}
+
+func (r Room) SetRole(u User, role string){
+  d := db.Db()
+  rr := RoomRole{}
+  d.Where("room_id = ? and user_id = ?", r.ID, u.ID).First(&rr)
+
+  if d.NewRecord(rr){
+    rr.Model = gorm.Model{}
+    rr.RoomID = uint64(r.ID)
+    rr.UserID = uint64(u.ID)
+  }
+  rr.Role = role
+  d.Save(&rr)
+
+  log.WithFields(log.Fields{
+    "roomId": r.ID,
+    "userId": u.ID,
+    "role": role,
+  }).Debug("Set room role")
+  r.PublishMembership(u, MembershipRole)
+}
+
+func (r Room) RemoveRole(u User){
+  // Hard delete, a soft deleted row would still hold the unique index
+  d := db.Db()
+  d.Unscoped().Where("room_id = ? and user_id = ?", r.ID, u.ID).Delete(RoomRole{})
+}
+
+func (r Room) IsOwner(u User) bool{
+  return r.RoleOf(u) == RoleOwner
+}
+
+func (r Room) CanModerate(u User) bool{
+  // Roles only count while in the room, except for its creator
+  if uint64(u.ID) != r.CreatorID && !r.HasMember(u){
+    return false
+  }
+  role := r.RoleOf(u)
+  return role == RoleOwner || role == RoleDJ
+}
+
+func (r Room) CanKick(u User, target User) bool{
+  /*
+    DJs can kick listeners, owners can kick anyone but other owners
+  */
+  if u.ID == target.ID || !r.HasMember(target){
+    return false
+  }
+
+  switch r.RoleOf(target){
+  case RoleOwner:
+    return false
+  case RoleDJ:
+    return r.IsOwner(u)
+  }
+  return r.CanModerate(u)
+}
+
+func (r *Room) Kick(u User){
+  // Kicked users lose their role, so they can't keep moderating or rejoin a private room
+  r.RemoveRole(u)
+  r.RemoveUser(u)
+}
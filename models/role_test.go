@@ -0,0 +1,74 @@
+package models_test
+
+import(
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/testdb"
+  "strconv"
+  "testing"
+)
+
+func reloadUser(u models.User) models.User{
+  fresh := models.User{}
+  fresh.ById(strconv.FormatUint(uint64(u.ID), 10))
+  return fresh
+}
+
+func TestKickedDJCantModerate(t *testing.T){
+  defer testdb.Open(t)()
+
+  owner := testdb.User(t, models.Oauth2{Provider: "google"})
+  dj := testdb.User(t, models.Oauth2{Provider: "google"})
+  room := testdb.Room(t, owner, "Office")
+
+  room.JoinUser(dj)
+  room.SetRole(dj, models.RoleDJ)
+  dj = reloadUser(dj)
+  if !room.CanModerate(dj){
+    t.Fatal("A DJ in the room can't moderate")
+  }
+
+  room.Kick(dj)
+  dj = reloadUser(dj)
+  if room.CanModerate(dj){
+    t.Error("A kicked DJ can still moderate")
+  }
+  if role := room.RoleOf(dj); role != models.RoleListener{
+    t.Errorf("A kicked DJ still has the %s role", role)
+  }
+
+  // Creators moderate their room from anywhere
+  if !room.CanModerate(owner){
+    t.Error("The creator can't moderate from outside the room")
+  }
+}
+
+func TestKickedUserCantSeePrivateRoom(t *testing.T){
+  defer testdb.Open(t)()
+  d := db.Db()
+
+  owner := testdb.User(t, models.Oauth2{Provider: "google"})
+  listener := testdb.User(t, models.Oauth2{Provider: "google"})
+  room := testdb.Room(t, owner, "Office")
+  room.Private = true
+  d.Save(&room)
+
+  room.SetRole(listener, models.RoleListener)
+  room.JoinUser(listener)
+  listener = reloadUser(listener)
+  if !room.VisibleTo(listener){
+    t.Fatal("A member can't see the private room")
+  }
+
+  room.Kick(listener)
+  listener = reloadUser(listener)
+  if room.VisibleTo(listener){
+    t.Error("A kicked user can still see the private room")
+  }
+
+  // Giving a role back works with the old row gone
+  room.SetRole(listener, models.RoleDJ)
+  if role := room.RoleOf(listener); role != models.RoleDJ{
+    t.Errorf("Role is %s after being set again", role)
+  }
+}
@@ -1,19 +1,41 @@
 package models
 
 import(
+  "crypto/rand"
+  "encoding/hex"
   "github.com/jinzhu/gorm"
   "github.com/samarudge/jukebox/db"
   "fmt"
+  "math"
   log "github.com/Sirupsen/logrus"
 )
 
+const(
+  BackendSpotify  = "spotify"
+  BackendMpd      = "mpd"
+  BackendStream   = "stream"
+)
+
 type Room struct{
   gorm.Model
-  Name      string
-  Active    bool
-  Creator   User
-  CreatorID uint64
-  Members   []User
+  Name          string
+  Active        bool
+  Creator       User
+  CreatorID     uint64
+  Members       []User
+  SkipThreshold float64 `sql:"default:0.5"`
+  Backend       string  `sql:"default:'spotify'"`
+  DeviceID      string
+  MpdAddress    string
+  Private       bool
+  InviteSecret  string
+  MaxQueuedPerUser  int
+  MaxTrackSeconds   int
+  BlockExplicit     bool
+  RepeatWindowHours int
+  QueueMode         string  `sql:"default:'votes'"`
+  AutoDJ            string
+  AutoDJPlaylist    string
 }
 
 func (r *Room) ById(roomId string){
@@ -30,22 +52,114 @@ func (r *Room) Create(user User, roomName string){
   r.Name = roomName
   r.Creator = user
   r.Active = true
+  r.SkipThreshold = DefaultSkipThreshold
+  r.Backend = BackendSpotify
+  r.QueueMode = QueueModeVotes
+  r.InviteSecret = newSecret()
 
   d.Create(&r)
+  r.SetRole(user, RoleOwner)
   log.WithFields(log.Fields{
     "roomName": r.Name,
     "roomId": r.ID,
   }).Debug("Created room")
 }
 
-func (r *Room) JoinUser(u User) error{
+func newSecret() string{
+  b := make([]byte, 16)
+  rand.Read(b)
+  return hex.EncodeToString(b)
+}
+
+func (r *Room) RegenerateInviteSecret(){
   d := db.Db()
-  d.Model(&r).Related(&u)
+  r.InviteSecret = newSecret()
+  d.Model(&r).UpdateColumn("invite_secret", r.InviteSecret)
+  log.WithFields(log.Fields{
+    "roomId": r.ID,
+  }).Info("Revoked room invites")
+}
+
+func (r Room) VisibleTo(u User) bool{
+  /*
+    Private rooms can only be seen by their members and anyone with a role
+  */
+  if !r.Private{
+    return true
+  }
+  if u.ID == 0{
+    return false
+  }
+
+  d := db.Db()
+  roles := 0
+  d.Model(&RoomRole{}).Where("room_id = ? and user_id = ?", r.ID, u.ID).Count(&roles)
+  return r.HasMember(u) || uint64(u.ID) == r.CreatorID || roles > 0
+}
+
+func VisibleRooms(u User) []Room{
+  d := db.Db()
+  var rooms []Room
+  d.Where("private = ? or id = ? or creator_id = ? or id in (select room_id from room_roles where user_id = ? and deleted_at is null)", false, u.RoomID, u.ID, u.ID).Order("name").Find(&rooms)
+  return rooms
+}
+
+func (r Room) CreatorUser() User{
+  d := db.Db()
+  u := User{}
+  d.Where("id = ?", r.CreatorID).First(&u)
+  return u
+}
 
-  r.Members = append(r.Members, u)
+func (r Room) MemberList() []User{
+  d := db.Db()
+  var users []User
+  d.Where("room_id = ?", r.ID).Order("last_seen desc").Find(&users)
+  return users
+}
+
+func (r Room) HasMember(u User) bool{
+  return u.RoomID == uint64(r.ID)
+}
+
+func (r Room) SkipPercent() int{
+  // Thresholds like 0.29 are stored a hair under, truncating would show 28
+  return int(math.Round(r.SkipThreshold*100))
+}
+
+func (r Room) BackendName() string{
+  if r.Backend == ""{
+    return BackendSpotify
+  }
+  return r.Backend
+}
+
+func (r Room) UsesSpotify() bool{
+  return r.BackendName() == BackendSpotify
+}
+
+func (r Room) UsesMpd() bool{
+  return r.BackendName() == BackendMpd
+}
+
+func (r Room) UsesStream() bool{
+  return r.BackendName() == BackendStream
+}
+
+func (r Room) PlayerConfigured() bool{
+  switch r.BackendName(){
+  case BackendSpotify:
+    return r.DeviceID != ""
+  case BackendMpd:
+    return r.MpdAddress != ""
+  case BackendStream:
+    return true
+  }
+  return false
+}
 
-  d.Save(&r)
-  return nil
+func (r Room) StreamUrl() string{
+  return fmt.Sprintf("/rooms/%d/stream", r.ID)
 }
 
 func (r Room) Url() string{
@@ -0,0 +1,108 @@
+package models
+
+import(
+  "sort"
+)
+
+const(
+  QueueModeVotes      = "votes"
+  QueueModeRoundRobin = "roundrobin"
+)
+
+// How far back to look when working out whose turn it is
+const roundRobinLookback = 50
+
+func ValidQueueMode(mode string) bool{
+  return mode == QueueModeVotes || mode == QueueModeRoundRobin
+}
+
+func (r Room) QueueModeName() string{
+  if r.QueueMode == ""{
+    return QueueModeVotes
+  }
+  return r.QueueMode
+}
+
+func (r Room) RoundRobin() bool{
+  return r.QueueModeName() == QueueModeRoundRobin
+}
+
+func (r Room) ProjectedQueue() []QueueEntry{
+  /*
+    The order tracks will actually play in. With votes this is just the
+    queue, round robin takes turns between everyone with something queued,
+    starting with whoever has waited longest since their last track played
+  */
+  queue := r.Queue()
+  if !r.RoundRobin(){
+    return queue
+  }
+
+  // Each person's tracks stay in vote order
+  turns := userTurns{lastPlayed: r.lastPlayed()}
+  byUser := map[uint64][]QueueEntry{}
+  var auto []QueueEntry
+  for _, e := range queue{
+    if e.AutoAdded{
+      auto = append(auto, e)
+      continue
+    }
+    if _, seen := byUser[e.AddedByID]; !seen{
+      turns.users = append(turns.users, e.AddedByID)
+    }
+    byUser[e.AddedByID] = append(byUser[e.AddedByID], e)
+  }
+  sort.Stable(turns)
+
+  var projected []QueueEntry
+  for round := 0; len(projected) < len(queue)-len(auto); round++{
+    for _, userId := range turns.users{
+      if round < len(byUser[userId]){
+        projected = append(projected, byUser[userId][round])
+      }
+    }
+  }
+  return append(projected, auto...)
+}
+
+func (r Room) lastPlayed() map[uint64]int{
+  /*
+    How many tracks ago each user last had something played, people who
+    haven't played anything recently aren't in the map
+  */
+  recent := r.History(roundRobinLookback)
+  if current, playing := r.NowPlaying(); playing{
+    recent = append([]QueueEntry{current}, recent...)
+  }
+
+  lastPlayed := map[uint64]int{}
+  for i, e := range recent{
+    if _, seen := lastPlayed[e.AddedByID]; !seen{
+      lastPlayed[e.AddedByID] = i
+    }
+  }
+  return lastPlayed
+}
+
+type userTurns struct{
+  users       []uint64
+  lastPlayed  map[uint64]int
+}
+
+func (t userTurns) Len() int{
+  return len(t.users)
+}
+
+func (t userTurns) Swap(i, j int){
+  t.users[i], t.users[j] = t.users[j], t.users[i]
+}
+
+func (t userTurns) Less(i, j int) bool{
+  a, aPlayed := t.lastPlayed[t.users[i]]
+  b, bPlayed := t.lastPlayed[t.users[j]]
+  if !aPlayed || !bPlayed{
+    // People who haven't had anything played recently go first
+    return !aPlayed && bPlayed
+  }
+  return a > b
+}
@@ -0,0 +1,181 @@
+package models
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/events"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "math"
+  "sync"
+  "time"
+)
+
+// Members seen within this window count towards the skip threshold
+const ActiveWindow = time.Minute*15
+
+const DefaultSkipThreshold = 0.5
+
+// Skips and advances are made one at a time per room, so votes landing together or as a track ends only skip one track
+var skipLocks = make(map[uint]*sync.Mutex)
+var skipLocksLock sync.Mutex
+
+type SkipVote struct{
+  gorm.Model
+  QueueEntryID  uint64
+  UserID        uint64
+}
+
+type SkipEvent struct{
+  gorm.Model
+  RoomID        uint64
+  QueueEntryID  uint64
+  TrackUri      string
+  Title         string
+  Artist        string
+  Votes         int
+  ActiveMembers int
+  SkippedAt     time.Time
+}
+
+type SkipCount struct{
+  TrackUri  string
+  Title     string
+  Artist    string
+  Skips     int
+}
+
+func (r Room) ActiveMembers() []User{
+  d := db.Db()
+  var users []User
+  d.Where("room_id = ? and last_seen > ?", r.ID, time.Now().UTC().Add(ActiveWindow*-1)).Find(&users)
+  return users
+}
+
+func (r Room) SkipVotesNeeded() int{
+  threshold := r.SkipThreshold
+  if threshold <= 0 || threshold > 1{
+    threshold = DefaultSkipThreshold
+  }
+
+  needed := int(math.Ceil(threshold * float64(len(r.ActiveMembers()))))
+  if needed < 1{
+    needed = 1
+  }
+  return needed
+}
+
+func (e QueueEntry) SkipVotes() int{
+  d := db.Db()
+  count := 0
+  d.Model(&SkipVote{}).Where("queue_entry_id = ?", e.ID).Count(&count)
+  return count
+}
+
+func (r *Room) VoteSkip(u User) (bool, error){
+  d := db.Db()
+
+  current, playing := r.NowPlaying()
+  if !playing{
+    return false, fmt.Errorf("Nothing is playing")
+  }
+
+  v := SkipVote{}
+  d.Where("queue_entry_id = ? and user_id = ?", current.ID, u.ID).First(&v)
+  if d.NewRecord(v){
+    v.Model = gorm.Model{}
+    v.QueueEntryID = uint64(current.ID)
+    v.UserID = uint64(u.ID)
+    d.Create(&v)
+  }
+
+  votes := current.SkipVotes()
+  active := len(r.ActiveMembers())
+  needed := r.SkipVotesNeeded()
+
+  log.WithFields(log.Fields{
+    "roomId": r.ID,
+    "entryId": current.ID,
+    "votes": votes,
+    "needed": needed,
+  }).Debug("Skip vote")
+
+  if events.Watched(r.ID){
+    events.Publish(r.ID, EventSkipVote, SkipVoteData{
+      EntryID: current.ID,
+      Votes: votes,
+      Needed: needed,
+    })
+  }
+
+  if votes < needed{
+    return false, nil
+  }
+
+  r.Skip(current, votes, active)
+  return true, nil
+}
+
+func (r *Room) ForceSkip() error{
+  current, playing := r.NowPlaying()
+  if !playing{
+    return fmt.Errorf("Nothing is playing")
+  }
+
+  r.Skip(current, current.SkipVotes(), len(r.ActiveMembers()))
+  return nil
+}
+
+func skipLock(roomId uint) *sync.Mutex{
+  skipLocksLock.Lock()
+  defer skipLocksLock.Unlock()
+
+  lock, found := skipLocks[roomId]
+  if !found{
+    lock = &sync.Mutex{}
+    skipLocks[roomId] = lock
+  }
+  return lock
+}
+
+func (r *Room) Skip(current QueueEntry, votes int, active int){
+  d := db.Db()
+
+  lock := skipLock(r.ID)
+  lock.Lock()
+  defer lock.Unlock()
+
+  // Another vote may have skipped it already, the next track didn't get these votes
+  if playing, found := r.NowPlaying(); !found || playing.ID != current.ID{
+    return
+  }
+
+  ev := SkipEvent{
+    RoomID: uint64(r.ID),
+    QueueEntryID: uint64(current.ID),
+    TrackUri: current.TrackUri,
+    Title: current.Title,
+    Artist: current.Artist,
+    Votes: votes,
+    ActiveMembers: active,
+    SkippedAt: time.Now().UTC(),
+  }
+  d.Create(&ev)
+
+  log.WithFields(log.Fields{
+    "roomId": r.ID,
+    "entryId": current.ID,
+    "track": current.TrackUri,
+  }).Info("Skipping track")
+
+  current.Status = QueueStatusSkipped
+  r.fireWebhooks(WebhookTrackSkipped, current.EventData())
+  r.advance(QueueStatusSkipped)
+}
+
+func MostSkipped(limit int) []SkipCount{
+  d := db.Db()
+  var counts []SkipCount
+  d.Table("skip_events").Select("track_uri, title, artist, count(*) as skips").Where("deleted_at is null").Group("track_uri, title, artist").Order("skips desc").Limit(limit).Scan(&counts)
+  return counts
+}
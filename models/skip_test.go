@@ -0,0 +1,56 @@
+package models_test
+
+import(
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/testdb"
+  "sync"
+  "testing"
+)
+
+func TestSkipPercent(t *testing.T){
+  for _, percent := range []int{1, 14, 28, 29, 33, 50, 57, 58, 99, 100}{
+    r := models.Room{SkipThreshold: float64(percent)/100}
+    if r.SkipPercent() != percent{
+      t.Errorf("SkipPercent() = %d for a threshold of %v", r.SkipPercent(), r.SkipThreshold)
+    }
+  }
+}
+
+func TestSkipOnlyOnce(t *testing.T){
+  /*
+    Votes that both saw the same track playing should skip it once, not
+    skip it and then whatever started after it
+  */
+  defer testdb.Open(t)()
+  d := db.Db()
+
+  u := testdb.User(t, models.Oauth2{Provider: "google"})
+  room := testdb.Room(t, u, "Office", "spotify:track:first", "spotify:track:second", "spotify:track:third")
+
+  first, playing := room.Advance(false)
+  if !playing{
+    t.Fatal("Nothing started playing")
+  }
+
+  var wg sync.WaitGroup
+  for i := 0; i < 5; i++{
+    wg.Add(1)
+    go func(){
+      defer wg.Done()
+      r := room
+      r.Skip(first, 1, 1)
+    }()
+  }
+  wg.Wait()
+
+  current, playing := room.NowPlaying()
+  if !playing || current.TrackUri != "spotify:track:second"{
+    t.Errorf("Now playing %q, want spotify:track:second", current.TrackUri)
+  }
+  count := 0
+  d.Model(&models.SkipEvent{}).Where("room_id = ?", room.ID).Count(&count)
+  if count != 1{
+    t.Errorf("%d skips recorded, want 1", count)
+  }
+}
@@ -4,6 +4,7 @@ import(
   "github.com/jinzhu/gorm"
   "github.com/samarudge/jukebox/auth"
   "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/spotify"
   log "github.com/Sirupsen/logrus"
 )
 
@@ -70,3 +71,17 @@ func (s *Spotify) LoadSystem(){
   d := db.Db()
   d.Where("system_account = ?", true).First(&s)
 }
+
+func (s Spotify) CanModifyPlaylists() bool{
+  return s.Auth().HasScopes(auth.SpotifyPlaylistModifyScopes)
+}
+
+func (s Spotify) CanControlPlayback() bool{
+  return s.Auth().HasScopes(auth.SpotifyPlaybackScopes)
+}
+
+func (s Spotify) Client() *spotify.Client{
+  a := s.Auth()
+  provider := a.LoadProvider()
+  return spotify.NewClient(provider.OauthClient(a.CreateToken()))
+}
@@ -0,0 +1,158 @@
+package models
+
+import(
+  "crypto/rand"
+  "crypto/sha256"
+  "encoding/hex"
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "strconv"
+  "strings"
+  "time"
+)
+
+const(
+  ScopeRead   = "read"
+  ScopeQueue  = "queue"
+  ScopePlayer = "player"
+  ScopeAdmin  = "admin"
+)
+
+var Scopes = []string{ScopeRead, ScopeQueue, ScopePlayer, ScopeAdmin}
+
+var ScopeDescriptions = map[string]string{
+  ScopeRead: "See rooms, queues and users",
+  ScopeQueue: "Join rooms, queue, vote and skip",
+  ScopePlayer: "Pause, resume and change the volume",
+  ScopeAdmin: "Administer users",
+}
+
+const tokenPrefix = "jbx_"
+
+// Saves writing to the database on every request
+const tokenUsedEvery = time.Minute
+
+type ApiToken struct{
+  gorm.Model
+  UserID    uint64
+  Name      string
+  // The start of the token so people can tell them apart, the rest is only stored hashed
+  Prefix    string
+  Hash      string
+  Scopes    string
+  LastUsed  time.Time
+}
+
+func ValidScope(scope string) bool{
+  for _, s := range Scopes{
+    if s == scope{
+      return true
+    }
+  }
+  return false
+}
+
+func hashToken(secret string) string{
+  sum := sha256.Sum256([]byte(secret))
+  return hex.EncodeToString(sum[:])
+}
+
+func (u User) CreateToken(name string, scopes []string) (ApiToken, string, error){
+  /*
+    Returns the token's secret, it can't be recovered after this
+  */
+  d := db.Db()
+
+  raw := make([]byte, 32)
+  if _, err := rand.Read(raw); err != nil{
+    return ApiToken{}, "", err
+  }
+  secret := tokenPrefix + hex.EncodeToString(raw)
+
+  t := ApiToken{
+    UserID: uint64(u.ID),
+    Name: name,
+    Prefix: secret[:len(tokenPrefix)+8],
+    Hash: hashToken(secret),
+    Scopes: strings.Join(scopes, " "),
+  }
+  d.Create(&t)
+
+  log.WithFields(log.Fields{
+    "userId": u.ID,
+    "tokenId": t.ID,
+    "scopes": t.Scopes,
+  }).Info("Created API token")
+  return t, secret, nil
+}
+
+func (u User) Tokens() []ApiToken{
+  d := db.Db()
+  var tokens []ApiToken
+  d.Where("user_id = ?", u.ID).Order("created_at desc").Find(&tokens)
+  return tokens
+}
+
+func (t *ApiToken) BySecret(secret string){
+  d := db.Db()
+  if !strings.HasPrefix(secret, tokenPrefix){
+    return
+  }
+  d.Where("hash = ?", hashToken(secret)).First(&t)
+}
+
+func (t *ApiToken) ById(tokenId string){
+  d := db.Db()
+  d.Where("id = ?", tokenId).First(&t)
+}
+
+func (t ApiToken) User() User{
+  u := User{}
+  u.ById(strconv.FormatUint(t.UserID, 10))
+  return u
+}
+
+func (t ApiToken) HasScope(scope string) bool{
+  for _, s := range strings.Fields(t.Scopes){
+    if s == scope{
+      return true
+    }
+  }
+  return false
+}
+
+func (t *ApiToken) Used(){
+  if time.Since(t.LastUsed) < tokenUsedEvery{
+    return
+  }
+
+  d := db.Db()
+  t.LastUsed = time.Now().UTC()
+  d.Model(&t).UpdateColumn("last_used", t.LastUsed)
+}
+
+func (t *ApiToken) Revoke(){
+  d := db.Db()
+  d.Delete(&t)
+  log.WithFields(log.Fields{
+    "userId": t.UserID,
+    "tokenId": t.ID,
+  }).Info("Revoked API token")
+}
+
+func (t ApiToken) CreatedStamp() string{
+  return t.CreatedAt.Format("Mon Jan 2 2006 15:04")
+}
+
+func (t ApiToken) LastUsedStamp() string{
+  if t.LastUsed.IsZero(){
+    return "Never"
+  }
+  return t.LastUsed.Format("Mon Jan 2 2006 15:04")
+}
+
+func (t ApiToken) Url() string{
+  return fmt.Sprintf("/users/%d/tokens/%d", t.UserID, t.ID)
+}
@@ -31,6 +31,18 @@ func (u *User) ByAuth(a *Oauth2){
   d.Where("oauth2_id = ?", a.ID).First(&u)
 }
 
+func (u *User) ByGoogleEmail(email string) bool{
+  // Google is the only provider whose username is a verified email
+  d := db.Db()
+  a := Oauth2{}
+  d.Where("provider = ? and lower(username) = lower(?)", "google", email).First(&a)
+  if d.NewRecord(a){
+    return false
+  }
+  u.ByAuth(&a)
+  return !d.NewRecord(*u)
+}
+
 func (u User) Auth() Oauth2{
   d := db.Db()
   a := Oauth2{}
@@ -48,6 +60,11 @@ func (u User) GetSpotify() Spotify{
   return spotify
 }
 
+func (u User) SpotifyNeedsRelink() bool{
+  hasSpotify, s := u.getSpotify()
+  return hasSpotify && !s.CanModifyPlaylists()
+}
+
 func (u User) getSpotify() (bool, Spotify){
   d := db.Db()
   s := Spotify{}
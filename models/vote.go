@@ -0,0 +1,63 @@
+package models
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  log "github.com/Sirupsen/logrus"
+)
+
+type Vote struct{
+  gorm.Model
+  QueueEntryID  uint64
+  UserID        uint64
+  Value         int
+}
+
+func (e *QueueEntry) Vote(u User, value int){
+  /*
+    One vote per user per entry, voting the same way twice withdraws the vote
+  */
+  d := db.Db()
+  v := Vote{}
+  d.Where("queue_entry_id = ? and user_id = ?", e.ID, u.ID).First(&v)
+
+  if d.NewRecord(v){
+    v.Model = gorm.Model{}
+    v.QueueEntryID = uint64(e.ID)
+    v.UserID = uint64(u.ID)
+  }
+
+  if v.Value == value{
+    v.Value = 0
+  } else {
+    v.Value = value
+  }
+  d.Save(&v)
+
+  e.UpdateScore()
+  log.WithFields(log.Fields{
+    "entryId": e.ID,
+    "userId": u.ID,
+    "vote": v.Value,
+    "score": e.Score,
+  }).Debug("Voted on queue entry")
+  e.publishVote()
+}
+
+func (e *QueueEntry) UpdateScore(){
+  d := db.Db()
+  var result struct{
+    Score int
+  }
+  d.Table("votes").Select("coalesce(sum(value), 0) as score").Where("queue_entry_id = ? and deleted_at is null", e.ID).Scan(&result)
+
+  e.Score = result.Score
+  d.Model(&e).UpdateColumn("score", e.Score)
+}
+
+func (e QueueEntry) UserVote(u User) int{
+  d := db.Db()
+  v := Vote{}
+  d.Where("queue_entry_id = ? and user_id = ?", e.ID, u.ID).First(&v)
+  return v.Value
+}
@@ -0,0 +1,451 @@
+package models
+
+import(
+  "bytes"
+  "crypto/hmac"
+  "crypto/sha256"
+  "encoding/hex"
+  "encoding/json"
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "net"
+  "net/http"
+  "net/url"
+  "strconv"
+  "strings"
+  "syscall"
+  "time"
+)
+
+/*
+  Room owners register URLs that get POSTed JSON when things happen in the
+  room. Every delivery is logged, failed ones are retried with backoff by
+  JobDeliverWebhooks
+*/
+
+const(
+  WebhookTrackStarted = "track.started"
+  WebhookTrackQueued  = "track.queued"
+  WebhookTrackSkipped = "track.skipped"
+  WebhookMemberJoined = "member.joined"
+)
+
+var WebhookEvents = []string{WebhookTrackStarted, WebhookTrackQueued, WebhookTrackSkipped, WebhookMemberJoined}
+
+var WebhookEventDescriptions = map[string]string{
+  WebhookTrackStarted: "A track starts playing",
+  WebhookTrackQueued: "Someone queues a track",
+  WebhookTrackSkipped: "The current track is skipped",
+  WebhookMemberJoined: "Someone joins the room",
+}
+
+const(
+  DeliveryPending   = "pending"
+  DeliveryDelivered = "delivered"
+  DeliveryFailed    = "failed"
+)
+
+// Retries wait webhookBackoff, then double that each time
+const webhookMaxAttempts = 6
+const webhookBackoff = 30*time.Second
+const webhookTimeout = 10*time.Second
+
+// Longer than any attempt takes, a delivery claimed by a server that stopped is retried after it
+const webhookClaim = time.Minute
+
+// How long the delivery log is kept
+const webhookLogAge = 7*24*time.Hour
+
+type Webhook struct{
+  gorm.Model
+  RoomID    uint64
+  CreatorID uint64
+  Url       string
+  // Shown to the room's owners so receivers can check the signature
+  Secret    string
+  // Space separated
+  Events    string
+}
+
+type WebhookDelivery struct{
+  gorm.Model
+  WebhookID     uint64
+  RoomID        uint64
+  // Copied so the log still makes sense once the webhook is deleted
+  Url           string
+  Event         string
+  Payload       string  `sql:"type:text"`
+  Status        string
+  Attempts      int
+  ResponseCode  int
+  LastError     string
+  NextAttempt   time.Time
+  DeliveredAt   time.Time
+}
+
+// Retries send the same payload, receivers can use DeliveryID to ignore repeats
+type WebhookPayload struct{
+  DeliveryID  uint        `json:"deliveryId"`
+  Event       string      `json:"event"`
+  Time        time.Time   `json:"time"`
+  RoomID      uint        `json:"roomId"`
+  RoomName    string      `json:"roomName"`
+  Data        interface{} `json:"data"`
+}
+
+func ValidWebhookEvent(event string) bool{
+  for _, e := range WebhookEvents{
+    if e == event{
+      return true
+    }
+  }
+  return false
+}
+
+func WebhookSignature(secret string, timestamp string, body []byte) string{
+  mac := hmac.New(sha256.New, []byte(secret))
+  fmt.Fprintf(mac, "%s.", timestamp)
+  mac.Write(body)
+  return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+  Any signed in user can own a room, so webhooks must not be a way to reach
+  the server's own network. Addresses are checked after DNS resolution when
+  connecting, and redirects aren't followed
+*/
+
+var webhookBlockedNets = parseNets(
+  "0.0.0.0/8",
+  "10.0.0.0/8",
+  "100.64.0.0/10",
+  "127.0.0.0/8",
+  "169.254.0.0/16",
+  "172.16.0.0/12",
+  "192.168.0.0/16",
+  "224.0.0.0/4",
+  "240.0.0.0/4",
+  "::/128",
+  "::1/128",
+  "fc00::/7",
+  "fe80::/10",
+  "ff00::/8",
+)
+
+func parseNets(cidrs ...string) []*net.IPNet{
+  var nets []*net.IPNet
+  for _, cidr := range cidrs{
+    _, n, err := net.ParseCIDR(cidr)
+    if err != nil{
+      panic(err)
+    }
+    nets = append(nets, n)
+  }
+  return nets
+}
+
+func webhookAllowedIP(ip net.IP) bool{
+  if ip4 := ip.To4(); ip4 != nil{
+    ip = ip4
+  }
+  for _, n := range webhookBlockedNets{
+    if n.Contains(ip){
+      return false
+    }
+  }
+  return true
+}
+
+func webhookDialControl(network string, address string, c syscall.RawConn) error{
+  host, _, err := net.SplitHostPort(address)
+  if err != nil{
+    return err
+  }
+  ip := net.ParseIP(host)
+  if ip == nil || !webhookAllowedIP(ip){
+    return fmt.Errorf("Webhooks can't be delivered to %s", host)
+  }
+  return nil
+}
+
+var webhookClient = &http.Client{
+  Timeout: webhookTimeout,
+  Transport: &http.Transport{
+    DialContext: (&net.Dialer{
+      Timeout: webhookTimeout,
+      Control: webhookDialControl,
+    }).DialContext,
+    TLSHandshakeTimeout: webhookTimeout,
+  },
+  // A redirect could send the signed request somewhere it was never meant to go
+  CheckRedirect: func(req *http.Request, via []*http.Request) error{
+    return http.ErrUseLastResponse
+  },
+}
+
+func (r Room) Webhooks() []Webhook{
+  d := db.Db()
+  var hooks []Webhook
+  d.Where("room_id = ?", r.ID).Order("created_at").Find(&hooks)
+  return hooks
+}
+
+func (r Room) AddWebhook(u User, rawUrl string, events []string) (Webhook, error){
+  h := Webhook{}
+
+  target, err := url.Parse(rawUrl)
+  if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == ""{
+    return h, fmt.Errorf("Webhook URLs must be absolute http or https URLs")
+  }
+  // Names are checked when delivering, literal addresses can be refused now
+  if ip := net.ParseIP(target.Hostname()); (ip != nil && !webhookAllowedIP(ip)) || strings.EqualFold(target.Hostname(), "localhost"){
+    return h, fmt.Errorf("Webhooks can't be delivered to %s", target.Hostname())
+  }
+  if len(events) == 0{
+    return h, fmt.Errorf("Pick at least one event for the webhook")
+  }
+  for _, e := range events{
+    if !ValidWebhookEvent(e){
+      return h, fmt.Errorf("Unknown webhook event %s", e)
+    }
+  }
+
+  d := db.Db()
+  h = Webhook{
+    RoomID: uint64(r.ID),
+    CreatorID: uint64(u.ID),
+    Url: target.String(),
+    Secret: newSecret(),
+    Events: strings.Join(events, " "),
+  }
+  d.Create(&h)
+
+  log.WithFields(log.Fields{
+    "roomId": r.ID,
+    "webhookId": h.ID,
+    "events": h.Events,
+  }).Info("Added webhook")
+  return h, nil
+}
+
+func (h *Webhook) ById(webhookId string){
+  d := db.Db()
+  d.Where("id = ?", webhookId).First(&h)
+}
+
+func (h *Webhook) Delete(){
+  d := db.Db()
+  d.Delete(&h)
+  log.WithFields(log.Fields{
+    "roomId": h.RoomID,
+    "webhookId": h.ID,
+  }).Info("Deleted webhook")
+}
+
+func (h Webhook) Subscribed(event string) bool{
+  for _, e := range strings.Fields(h.Events){
+    if e == event{
+      return true
+    }
+  }
+  return false
+}
+
+func (h Webhook) DeleteUrl() string{
+  return fmt.Sprintf("/rooms/%d/webhooks/%d/delete", h.RoomID, h.ID)
+}
+
+func (r Room) fireWebhooks(event string, data interface{}){
+  d := db.Db()
+  var hooks []Webhook
+  d.Where("room_id = ?", r.ID).Find(&hooks)
+
+  for _, h := range hooks{
+    if !h.Subscribed(event){
+      continue
+    }
+
+    w := WebhookDelivery{
+      WebhookID: uint64(h.ID),
+      RoomID: uint64(r.ID),
+      Url: h.Url,
+      Event: event,
+      Status: DeliveryPending,
+      NextAttempt: time.Now().UTC(),
+    }
+    d.Create(&w)
+
+    payload, err := json.Marshal(WebhookPayload{
+      DeliveryID: w.ID,
+      Event: event,
+      Time: w.CreatedAt,
+      RoomID: r.ID,
+      RoomName: r.Name,
+      Data: data,
+    })
+    if err != nil{
+      log.WithFields(log.Fields{
+        "webhookId": h.ID,
+        "error": err,
+      }).Error("Could not encode webhook payload")
+      d.Model(&w).Updates(map[string]interface{}{
+        "status": DeliveryFailed,
+        "last_error": err.Error(),
+      })
+      continue
+    }
+    w.Payload = string(payload)
+    d.Model(&w).UpdateColumn("payload", w.Payload)
+
+    // Don't make whatever fired the event wait for the receiver
+    go w.deliver()
+  }
+}
+
+func (w *WebhookDelivery) claim() bool{
+  /*
+    Pushes the next attempt back so the job and the first attempt can't
+    both send the same delivery
+  */
+  d := db.Db()
+  now := time.Now().UTC()
+  until := now.Add(webhookClaim)
+  claimed := d.Model(&WebhookDelivery{}).Where("id = ? and status = ? and next_attempt <= ?", w.ID, DeliveryPending, now).UpdateColumn("next_attempt", until).RowsAffected == 1
+  if claimed{
+    w.NextAttempt = until
+  }
+  return claimed
+}
+
+func (w *WebhookDelivery) deliver(){
+  if !w.claim(){
+    return
+  }
+
+  d := db.Db()
+  h := Webhook{}
+  h.ById(strconv.FormatUint(w.WebhookID, 10))
+
+  var err error
+  if d.NewRecord(h){
+    err = fmt.Errorf("The webhook was deleted")
+    w.Attempts = webhookMaxAttempts - 1
+  } else {
+    w.ResponseCode, err = h.send(w.Event, w.ID, []byte(w.Payload))
+  }
+  w.Attempts++
+
+  if err == nil{
+    w.Status = DeliveryDelivered
+    w.DeliveredAt = time.Now().UTC()
+    w.LastError = ""
+  } else {
+    w.LastError = err.Error()
+    if w.Attempts >= webhookMaxAttempts{
+      w.Status = DeliveryFailed
+    } else {
+      w.NextAttempt = time.Now().UTC().Add(webhookBackoff << uint(w.Attempts-1))
+    }
+  }
+  d.Save(&w)
+
+  log.WithFields(log.Fields{
+    "deliveryId": w.ID,
+    "webhookId": w.WebhookID,
+    "event": w.Event,
+    "attempt": w.Attempts,
+    "status": w.Status,
+    "error": w.LastError,
+  }).Debug("Webhook delivery")
+}
+
+func (h Webhook) send(event string, deliveryId uint, body []byte) (int, error){
+  req, err := http.NewRequest("POST", h.Url, bytes.NewReader(body))
+  if err != nil{
+    return 0, err
+  }
+
+  // The timestamp is signed too so a captured delivery can't be replayed later
+  timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+  req.Header.Set("Content-Type", "application/json")
+  req.Header.Set("User-Agent", "Jukebox-Webhook")
+  req.Header.Set("X-Jukebox-Event", event)
+  req.Header.Set("X-Jukebox-Delivery", strconv.FormatUint(uint64(deliveryId), 10))
+  req.Header.Set("X-Jukebox-Timestamp", timestamp)
+  req.Header.Set("X-Jukebox-Signature", WebhookSignature(h.Secret, timestamp, body))
+
+  rsp, err := webhookClient.Do(req)
+  if err != nil{
+    return 0, err
+  }
+  defer rsp.Body.Close()
+
+  if rsp.StatusCode < 200 || rsp.StatusCode > 299{
+    return rsp.StatusCode, fmt.Errorf("Receiver answered %s", rsp.Status)
+  }
+  return rsp.StatusCode, nil
+}
+
+func (w *WebhookDelivery) ById(deliveryId string){
+  d := db.Db()
+  d.Where("id = ?", deliveryId).First(&w)
+}
+
+func (w *WebhookDelivery) Retry(){
+  // Another full set of attempts, from now
+  d := db.Db()
+  w.Status = DeliveryPending
+  w.Attempts = 0
+  w.NextAttempt = time.Now().UTC()
+  d.Save(&w)
+  go w.deliver()
+}
+
+func RecentDeliveries(limit int) []WebhookDelivery{
+  d := db.Db()
+  var deliveries []WebhookDelivery
+  d.Order("created_at desc").Limit(limit).Find(&deliveries)
+  return deliveries
+}
+
+func (w WebhookDelivery) Delivered() bool{
+  return w.Status == DeliveryDelivered
+}
+
+func (w WebhookDelivery) Failed() bool{
+  return w.Status == DeliveryFailed
+}
+
+func (w WebhookDelivery) Pending() bool{
+  return w.Status == DeliveryPending
+}
+
+func (w WebhookDelivery) CreatedStamp() string{
+  return w.CreatedAt.Format("Mon Jan 2 2006 15:04:05 MST")
+}
+
+func (w WebhookDelivery) NextAttemptStamp() string{
+  return w.NextAttempt.Format("15:04:05 MST")
+}
+
+func (w WebhookDelivery) RetryUrl() string{
+  return fmt.Sprintf("/admin/webhooks/%d/retry", w.ID)
+}
+
+func (w WebhookDelivery) RoomUrl() string{
+  return fmt.Sprintf("/rooms/%d", w.RoomID)
+}
+
+func JobDeliverWebhooks(){
+  d := db.Db()
+  var due []WebhookDelivery
+  d.Where("status = ? and next_attempt <= ?", DeliveryPending, time.Now().UTC()).Find(&due)
+  // Each on its own so a slow receiver doesn't hold up the rest, or the other jobs
+  for i := range due{
+    go due[i].deliver()
+  }
+
+  d.Unscoped().Where("created_at < ?", time.Now().UTC().Add(-webhookLogAge)).Delete(&WebhookDelivery{})
+}
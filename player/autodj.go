@@ -0,0 +1,246 @@
+package player
+
+import(
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/spotify"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "math"
+  "math/rand"
+  "sort"
+  "strings"
+  "time"
+)
+
+// How long to wait before trying again when the auto-DJ couldn't find anything
+var AutoDJRetry = time.Second*30
+
+// The auto-DJ won't pick anything played in this many recent tracks
+const autoDJRecentTracks = 50
+
+// How many played tracks to choose from when picking from history
+const autoDJHistorySize = 500
+
+const autoDJPageSize = 50
+
+func init(){
+  rand.Seed(time.Now().UnixNano())
+}
+
+func (p *Player) autoFill(room models.Room, backend Backend) error{
+  /*
+    Keep one track queued while the room has nothing else, people's picks
+    are always ordered ahead of it so it only plays if nobody adds anything
+  */
+  if !room.AutoDJEnabled() || len(room.Queue()) > 0{
+    return nil
+  }
+  if time.Since(p.autoFilledAt) < AutoDJRetry{
+    return nil
+  }
+  p.autoFilledAt = time.Now()
+
+  var candidates []models.QueueEntry
+  var err error
+  switch room.AutoDJ{
+  case models.AutoDJPlaylist:
+    candidates, err = p.playlistCandidates(room)
+  case models.AutoDJHistory:
+    candidates = historyCandidates(room)
+  case models.AutoDJRecommendations:
+    candidates, err = p.recommendedCandidates(room)
+  }
+  if err != nil{
+    return err
+  }
+
+  recent := make(map[string]bool)
+  for _, e := range room.History(autoDJRecentTracks){
+    recent[e.TrackUri] = true
+  }
+  if current, playing := room.NowPlaying(); playing{
+    recent[current.TrackUri] = true
+  }
+
+  for _, e := range candidates{
+    if recent[e.TrackUri] || !backend.CanPlay(e) || room.CheckTrackPolicy(e) != nil{
+      continue
+    }
+
+    e.AutoAdded = true
+    room.Enqueue(models.User{}, &e)
+    p.autoFilledAt = time.Time{}
+
+    log.WithFields(log.Fields{
+      "roomId": room.ID,
+      "source": room.AutoDJ,
+      "track": e.TrackUri,
+    }).Info("Auto-DJ queued track")
+    return nil
+  }
+
+  log.WithFields(log.Fields{
+    "roomId": room.ID,
+    "source": room.AutoDJ,
+  }).Debug("Auto-DJ found nothing to queue")
+  return nil
+}
+
+func (p *Player) spotifyClient() (*spotify.Client, error){
+  s := models.Spotify{}
+  s.LoadSystem()
+  if !s.Exists(){
+    return nil, fmt.Errorf("System Spotify account not configured")
+  }
+
+  client := s.Client()
+  if p.ApiUrl != ""{
+    client.BaseUrl = p.ApiUrl
+  }
+  return client, nil
+}
+
+func trackEntry(t spotify.Track) models.QueueEntry{
+  return models.QueueEntry{
+    TrackUri: t.Uri,
+    Title: t.Name,
+    Artist: t.ArtistNames(),
+    DurationMs: t.DurationMs,
+    Explicit: t.Explicit,
+  }
+}
+
+func shuffled(entries []models.QueueEntry) []models.QueueEntry{
+  for i := range entries{
+    j := rand.Intn(i+1)
+    entries[i], entries[j] = entries[j], entries[i]
+  }
+  return entries
+}
+
+func (p *Player) playlistCandidates(room models.Room) ([]models.QueueEntry, error){
+  if room.AutoDJPlaylist == ""{
+    return nil, fmt.Errorf("No auto-DJ playlist configured")
+  }
+
+  client, err := p.spotifyClient()
+  if err != nil{
+    return nil, err
+  }
+
+  playlistId := spotify.PlaylistId(room.AutoDJPlaylist)
+  if !spotify.ValidId(playlistId){
+    return nil, fmt.Errorf("Invalid auto-DJ playlist %s", room.AutoDJPlaylist)
+  }
+  page, err := client.PlaylistTracks(playlistId, autoDJPageSize, 0)
+  if err != nil{
+    return nil, err
+  }
+
+  // Start somewhere random in long playlists
+  if page.Total > autoDJPageSize{
+    page, err = client.PlaylistTracks(playlistId, autoDJPageSize, rand.Intn(page.Total-autoDJPageSize+1))
+    if err != nil{
+      return nil, err
+    }
+  }
+
+  var entries []models.QueueEntry
+  for _, item := range page.Items{
+    if item.Track.Uri != ""{
+      entries = append(entries, trackEntry(item.Track))
+    }
+  }
+  return shuffled(entries), nil
+}
+
+type weightedEntry struct{
+  entry   models.QueueEntry
+  weight  float64
+  key     float64
+}
+
+type byWeightedKey []*weightedEntry
+
+func (w byWeightedKey) Len() int{
+  return len(w)
+}
+
+func (w byWeightedKey) Swap(i, j int){
+  w[i], w[j] = w[j], w[i]
+}
+
+func (w byWeightedKey) Less(i, j int) bool{
+  return w[i].key > w[j].key
+}
+
+func historyCandidates(room models.Room) []models.QueueEntry{
+  /*
+    Every track that has played through in the room, ordered by a random
+    draw weighted by how well it was voted each time it played
+  */
+  tracks := make(map[string]*weightedEntry)
+  var weighted byWeightedKey
+  for _, e := range room.PlayedEntries(autoDJHistorySize){
+    if e.Score < 0{
+      continue
+    }
+
+    w, seen := tracks[e.TrackUri]
+    if !seen{
+      w = &weightedEntry{entry: e}
+      tracks[e.TrackUri] = w
+      weighted = append(weighted, w)
+    }
+    w.weight += float64(1 + e.Score)
+  }
+
+  for _, w := range weighted{
+    w.key = math.Pow(rand.Float64(), 1/w.weight)
+  }
+  sort.Sort(weighted)
+
+  var entries []models.QueueEntry
+  for _, w := range weighted{
+    entries = append(entries, models.QueueEntry{
+      TrackUri: w.entry.TrackUri,
+      Title: w.entry.Title,
+      Artist: w.entry.Artist,
+      DurationMs: w.entry.DurationMs,
+      Explicit: w.entry.Explicit,
+    })
+  }
+  return entries
+}
+
+func (p *Player) recommendedCandidates(room models.Room) ([]models.QueueEntry, error){
+  // Spotify takes up to five seeds
+  var seeds []string
+  for _, e := range room.History(autoDJRecentTracks){
+    if len(seeds) == 5{
+      break
+    }
+    if strings.HasPrefix(e.TrackUri, "spotify:track:") && !e.Skipped(){
+      seeds = append(seeds, strings.TrimPrefix(e.TrackUri, "spotify:track:"))
+    }
+  }
+  if len(seeds) == 0{
+    return nil, fmt.Errorf("Nothing has played in this room to base recommendations on")
+  }
+
+  client, err := p.spotifyClient()
+  if err != nil{
+    return nil, err
+  }
+
+  tracks, err := client.Recommendations(seeds, autoDJPageSize)
+  if err != nil{
+    return nil, err
+  }
+
+  var entries []models.QueueEntry
+  for _, t := range tracks{
+    entries = append(entries, trackEntry(t))
+  }
+  return entries, nil
+}
@@ -0,0 +1,51 @@
+package player
+
+import(
+  "github.com/samarudge/jukebox/models"
+  "fmt"
+  "time"
+)
+
+type Status struct{
+  Playing   bool
+  TrackUri  string
+  Position  time.Duration
+  Duration  time.Duration
+}
+
+func (s Status) Remaining() time.Duration{
+  return s.Duration - s.Position
+}
+
+type Backend interface{
+  // Replace whatever is playing with the entry
+  Play(e models.QueueEntry)     error
+  Pause()                       error
+  Resume()                      error
+  // Stop the current track, moving on to anything enqueued on the backend
+  Skip()                        error
+  SetVolume(percent int)        error
+  Status()                      (Status, error)
+  // Add the entry to the backend's own queue so it follows without a gap
+  Enqueue(e models.QueueEntry)  error
+  CanPlay(e models.QueueEntry)  bool
+}
+
+func NewBackend(room models.Room) (Backend, error){
+  switch room.BackendName(){
+  case models.BackendSpotify:
+    return &SpotifyConnect{
+      DeviceID: room.DeviceID,
+    }, nil
+  case models.BackendMpd:
+    return &Mpd{
+      Address: room.MpdAddress,
+      Password: MpdPassword,
+    }, nil
+  case models.BackendStream:
+    return &Stream{
+      RoomID: room.ID,
+    }, nil
+  }
+  return nil, fmt.Errorf("Unknown playback backend %s", room.Backend)
+}
@@ -0,0 +1,207 @@
+package player
+
+import(
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/spotify"
+  log "github.com/Sirupsen/logrus"
+  "bufio"
+  "fmt"
+  "net"
+  "strconv"
+  "strings"
+  "time"
+)
+
+var MpdTimeout = time.Second*5
+
+// Sent to every MPD server, set from config
+var MpdPassword string
+
+// Speaks the MPD text protocol, also works against Mopidy which accepts spotify: uris
+type Mpd struct{
+  Address   string
+  Password  string
+}
+
+func mpdQuote(arg string) (string, error){
+  // Commands end at a newline, so one in an argument would start another command
+  for _, r := range arg{
+    if r < 0x20 || r == 0x7f{
+      return "", fmt.Errorf("MPD arguments can't contain control characters")
+    }
+  }
+  arg = strings.Replace(arg, `\`, `\\`, -1)
+  arg = strings.Replace(arg, `"`, `\"`, -1)
+  return fmt.Sprintf(`"%s"`, arg), nil
+}
+
+func (b *Mpd) command(commands ...string) (map[string]string, error){
+  /*
+    Run the commands as a single command list, returning the key/value
+    pairs from the response
+  */
+  conn, err := net.DialTimeout("tcp", b.Address, MpdTimeout)
+  if err != nil{
+    return nil, err
+  }
+  defer conn.Close()
+  conn.SetDeadline(time.Now().Add(MpdTimeout))
+
+  reader := bufio.NewReader(conn)
+  greeting, err := reader.ReadString('\n')
+  if err != nil{
+    return nil, err
+  }
+  if !strings.HasPrefix(greeting, "OK MPD"){
+    return nil, fmt.Errorf("Unexpected MPD greeting: %s", strings.TrimSpace(greeting))
+  }
+
+  if b.Password != ""{
+    password, err := mpdQuote(b.Password)
+    if err != nil{
+      return nil, err
+    }
+    commands = append([]string{fmt.Sprintf("password %s", password)}, commands...)
+  }
+
+  var request string
+  if len(commands) == 1{
+    request = commands[0] + "\n"
+  } else {
+    request = "command_list_begin\n" + strings.Join(commands, "\n") + "\ncommand_list_end\n"
+  }
+
+  log.WithFields(log.Fields{
+    "address": b.Address,
+    "commands": commands,
+  }).Debug("MPD command")
+
+  if _, err := conn.Write([]byte(request)); err != nil{
+    return nil, err
+  }
+
+  response := make(map[string]string)
+  for{
+    line, err := reader.ReadString('\n')
+    if err != nil{
+      return nil, err
+    }
+    line = strings.TrimRight(line, "\n")
+
+    if line == "OK"{
+      return response, nil
+    }
+    if strings.HasPrefix(line, "ACK "){
+      return nil, fmt.Errorf("MPD error: %s", strings.TrimPrefix(line, "ACK "))
+    }
+
+    parts := strings.SplitN(line, ": ", 2)
+    if len(parts) == 2{
+      response[parts[0]] = parts[1]
+    }
+  }
+}
+
+func mpdUri(e models.QueueEntry) (string, error){
+  /*
+    Only library tracks and Spotify tracks for Mopidy, MPD would happily
+    fetch file:// or http:// URIs from anywhere it can reach
+  */
+  if e.IsLocal(){
+    // Library paths are relative so MPD's music_directory should be the library root
+    t := models.LibraryTrack{}
+    if !t.ByUri(e.TrackUri){
+      return "", fmt.Errorf("Track not found in the library")
+    }
+    return mpdQuote(t.Path)
+  }
+  if _, ok := spotify.TrackId(e.TrackUri); ok{
+    return mpdQuote(e.TrackUri)
+  }
+  return "", fmt.Errorf("MPD can't play %s", e.TrackUri)
+}
+
+func (b *Mpd) CanPlay(e models.QueueEntry) bool{
+  _, err := mpdUri(e)
+  return err == nil
+}
+
+func (b *Mpd) Play(e models.QueueEntry) error{
+  uri, err := mpdUri(e)
+  if err != nil{
+    return err
+  }
+  _, err = b.command("clear", fmt.Sprintf("add %s", uri), "play")
+  return err
+}
+
+func (b *Mpd) Pause() error{
+  _, err := b.command("pause 1")
+  return err
+}
+
+func (b *Mpd) Resume() error{
+  _, err := b.command("pause 0")
+  return err
+}
+
+func (b *Mpd) Skip() error{
+  // MPD stops by itself when there is nothing after the current song
+  _, err := b.command("next")
+  return err
+}
+
+func (b *Mpd) SetVolume(percent int) error{
+  _, err := b.command(fmt.Sprintf("setvol %d", percent))
+  return err
+}
+
+func mpdSeconds(value string) time.Duration{
+  seconds, _ := strconv.ParseFloat(value, 64)
+  return time.Duration(seconds*float64(time.Second))
+}
+
+func mpdTrackUri(file string) string{
+  // MPD reports library tracks by path, the queue knows them by their library uri
+  if _, ok := spotify.TrackId(file); ok || file == ""{
+    return file
+  }
+  t := models.LibraryTrack{}
+  t.ByPath(file)
+  if t.ID == 0{
+    return file
+  }
+  return t.Uri()
+}
+
+func (b *Mpd) Status() (Status, error){
+  response, err := b.command("status", "currentsong")
+  if err != nil{
+    return Status{}, err
+  }
+
+  status := Status{
+    Playing: response["state"] == "play",
+    TrackUri: mpdTrackUri(response["file"]),
+    Position: mpdSeconds(response["elapsed"]),
+    Duration: mpdSeconds(response["duration"]),
+  }
+
+  // Older MPD versions only report "time: elapsed:total"
+  if status.Duration == 0{
+    times := strings.SplitN(response["time"], ":", 2)
+    if len(times) == 2{
+      status.Duration = mpdSeconds(times[1])
+    }
+  }
+  return status, nil
+}
+
+func (b *Mpd) Enqueue(e models.QueueEntry) error{
+  uri, err := mpdUri(e)
+  if err != nil{
+    return err
+  }
+  _, err = b.command(fmt.Sprintf("add %s", uri))
+  return err
+}
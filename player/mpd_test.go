@@ -0,0 +1,294 @@
+package player
+
+import(
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/testdb"
+  "bufio"
+  "net"
+  "reflect"
+  "strings"
+  "testing"
+  "time"
+)
+
+const mpdGreeting = "OK MPD 0.23.5\n"
+
+// Answers every connection with the same reply, recording the commands sent
+type fakeMpd struct{
+  listener  net.Listener
+  greeting  string
+  reply     string
+  requests  chan []string
+}
+
+func newFakeMpd(t *testing.T, greeting string, reply string) *fakeMpd{
+  l, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil{
+    t.Fatal(err)
+  }
+  f := &fakeMpd{
+    listener: l,
+    greeting: greeting,
+    reply: reply,
+    requests: make(chan []string, 10),
+  }
+  go f.serve()
+  return f
+}
+
+func (f *fakeMpd) serve(){
+  for{
+    conn, err := f.listener.Accept()
+    if err != nil{
+      return
+    }
+    go f.handle(conn)
+  }
+}
+
+func (f *fakeMpd) handle(conn net.Conn){
+  defer conn.Close()
+  conn.SetDeadline(time.Now().Add(time.Second))
+  conn.Write([]byte(f.greeting))
+
+  reader := bufio.NewReader(conn)
+  var lines []string
+  for{
+    line, err := reader.ReadString('\n')
+    if err != nil{
+      break
+    }
+    lines = append(lines, strings.TrimRight(line, "\n"))
+    if lines[0] != "command_list_begin" || lines[len(lines)-1] == "command_list_end"{
+      break
+    }
+  }
+  f.requests <- lines
+  conn.Write([]byte(f.reply))
+}
+
+func (f *fakeMpd) backend(password string) *Mpd{
+  return &Mpd{Address: f.listener.Addr().String(), Password: password}
+}
+
+func (f *fakeMpd) request(t *testing.T) []string{
+  select{
+  case lines := <-f.requests:
+    return lines
+  case <-time.After(time.Second):
+    t.Fatal("Nothing was sent to MPD")
+  }
+  return nil
+}
+
+func (f *fakeMpd) Close(){
+  f.listener.Close()
+}
+
+func TestMpdQuote(t *testing.T){
+  tests := []struct{
+    arg     string
+    quoted  string
+    ok      bool
+  }{
+    {"spotify:track:4uLU6hMCjMI75M1A2tKUQC", `"spotify:track:4uLU6hMCjMI75M1A2tKUQC"`, true},
+    {"Artist/Album/01 Track.mp3", `"Artist/Album/01 Track.mp3"`, true},
+    {`Say "Hello"`, `"Say \"Hello\""`, true},
+    {`AC\DC`, `"AC\\DC"`, true},
+    {`\"`, `"\\\""`, true},
+    {"Bjørk", `"Bjørk"`, true},
+    {"", `""`, true},
+    {"track.mp3\"\nclear", "", false},
+    {"track.mp3\rplay", "", false},
+    {"tab\there", "", false},
+    {"null\x00", "", false},
+    {"delete\x7f", "", false},
+  }
+
+  for _, test := range tests{
+    quoted, err := mpdQuote(test.arg)
+    if test.ok && (err != nil || quoted != test.quoted){
+      t.Errorf("mpdQuote(%q) = %q, %v, want %q", test.arg, quoted, err, test.quoted)
+    }
+    if !test.ok && err == nil{
+      t.Errorf("mpdQuote(%q) = %q, should have been refused", test.arg, quoted)
+    }
+  }
+}
+
+func TestMpdSingleCommand(t *testing.T){
+  f := newFakeMpd(t, mpdGreeting, "OK\n")
+  defer f.Close()
+
+  if err := f.backend("").Pause(); err != nil{
+    t.Fatal(err)
+  }
+  if lines := f.request(t); !reflect.DeepEqual(lines, []string{"pause 1"}){
+    t.Errorf("Sent %q, want a single pause command", lines)
+  }
+}
+
+func TestMpdCommandList(t *testing.T){
+  f := newFakeMpd(t, mpdGreeting, "OK\n")
+  defer f.Close()
+
+  e := models.QueueEntry{TrackUri: "spotify:track:4uLU6hMCjMI75M1A2tKUQC"}
+  if err := f.backend(`pass"word`).Play(e); err != nil{
+    t.Fatal(err)
+  }
+
+  want := []string{
+    "command_list_begin",
+    `password "pass\"word"`,
+    "clear",
+    `add "spotify:track:4uLU6hMCjMI75M1A2tKUQC"`,
+    "play",
+    "command_list_end",
+  }
+  if lines := f.request(t); !reflect.DeepEqual(lines, want){
+    t.Errorf("Sent %q, want %q", lines, want)
+  }
+}
+
+func TestMpdPasswordControlCharacters(t *testing.T){
+  f := newFakeMpd(t, mpdGreeting, "OK\n")
+  defer f.Close()
+
+  if err := f.backend("secret\nclear").Skip(); err == nil{
+    t.Error("A password with a newline was sent")
+  }
+  if lines := f.request(t); len(lines) != 0{
+    t.Errorf("Sent %q after refusing the password", lines)
+  }
+}
+
+func TestMpdAck(t *testing.T){
+  f := newFakeMpd(t, mpdGreeting, "ACK [50@1] {add} No such directory\n")
+  defer f.Close()
+
+  err := f.backend("").Enqueue(models.QueueEntry{TrackUri: "spotify:track:4uLU6hMCjMI75M1A2tKUQC"})
+  if err == nil{
+    t.Fatal("ACK was not returned as an error")
+  }
+  if err.Error() != "MPD error: [50@1] {add} No such directory"{
+    t.Errorf("Unexpected error %q", err)
+  }
+}
+
+func TestMpdGreeting(t *testing.T){
+  f := newFakeMpd(t, "HTTP/1.1 400 Bad Request\n", "OK\n")
+  defer f.Close()
+
+  if err := f.backend("").Resume(); err == nil || !strings.Contains(err.Error(), "Unexpected MPD greeting"){
+    t.Errorf("Unexpected error %v", err)
+  }
+}
+
+func TestMpdStatus(t *testing.T){
+  f := newFakeMpd(t, mpdGreeting, "volume: 80\nstate: play\nelapsed: 12.500\nduration: 215.000\n" +
+    "file: spotify:track:4uLU6hMCjMI75M1A2tKUQC\nTitle: Mr. Brightside\nOK\n")
+  defer f.Close()
+
+  status, err := f.backend("").Status()
+  if err != nil{
+    t.Fatal(err)
+  }
+  want := Status{
+    Playing: true,
+    TrackUri: "spotify:track:4uLU6hMCjMI75M1A2tKUQC",
+    Position: 12500*time.Millisecond,
+    Duration: 215*time.Second,
+  }
+  if !reflect.DeepEqual(status, want){
+    t.Errorf("Status = %+v, want %+v", status, want)
+  }
+  if lines := f.request(t); !reflect.DeepEqual(lines, []string{"command_list_begin", "status", "currentsong", "command_list_end"}){
+    t.Errorf("Sent %q", lines)
+  }
+}
+
+func TestMpdStatusOldTime(t *testing.T){
+  defer testdb.Open(t)()
+  f := newFakeMpd(t, mpdGreeting, "state: pause\ntime: 12:215\nfile: Artist/Album/01 Track.mp3\nOK\n")
+  defer f.Close()
+
+  status, err := f.backend("").Status()
+  if err != nil{
+    t.Fatal(err)
+  }
+  if status.Playing || status.Duration != 215*time.Second{
+    t.Errorf("Status = %+v", status)
+  }
+  // Files outside the library are reported as they are
+  if status.TrackUri != "Artist/Album/01 Track.mp3"{
+    t.Errorf("TrackUri = %q", status.TrackUri)
+  }
+}
+
+func TestMpdPlaysLibraryTrack(t *testing.T){
+  defer playerTestSettings(0, 0)()
+  defer testdb.Open(t)()
+  d := db.Db()
+
+  f := newFakeMpd(t, mpdGreeting, "state: play\nelapsed: 100.000\nduration: 215.000\nfile: Artist/Album/01 Track.mp3\nOK\n")
+  defer f.Close()
+
+  track := models.LibraryTrack{Path: "Artist/Album/01 Track.mp3", DurationMs: 215000}
+  d.Create(&track)
+
+  u := testdb.User(t, models.Oauth2{Provider: "google"})
+  room := testdb.Room(t, u, "Office", track.Uri(), "spotify:track:second")
+  room.Backend = models.BackendMpd
+  room.MpdAddress = f.listener.Addr().String()
+  d.Save(&room)
+
+  p := NewPlayer(room.ID)
+  if err := p.Tick(); err != nil{
+    t.Fatal(err)
+  }
+  want := []string{"command_list_begin", "clear", `add "Artist/Album/01 Track.mp3"`, "play", "command_list_end"}
+  if lines := f.request(t); !reflect.DeepEqual(lines, want){
+    t.Errorf("Sent %q, want %q", lines, want)
+  }
+
+  // MPD reports the file it is playing, which is still the library track
+  if err := p.Tick(); err != nil{
+    t.Fatal(err)
+  }
+  if uri := nowPlaying(room); uri != track.Uri(){
+    t.Errorf("Now playing %q while MPD is half way through %s", uri, track.Uri())
+  }
+}
+
+func TestMpdCanPlay(t *testing.T){
+  b := &Mpd{}
+  tests := map[string]bool{
+    "spotify:track:4uLU6hMCjMI75M1A2tKUQC": true,
+    "spotify:track:": false,
+    "spotify:track:4uLU6hMC\nclear": false,
+    "spotify:playlist:37i9dQZF1DXcBWIGoYBM5M": false,
+    "file:///etc/passwd": false,
+    "http://169.254.169.254/latest/meta-data": false,
+    "": false,
+  }
+  for uri, ok := range tests{
+    if b.CanPlay(models.QueueEntry{TrackUri: uri}) != ok{
+      t.Errorf("CanPlay(%q) should be %t", uri, ok)
+    }
+  }
+}
+
+func TestMpdConfiguredPassword(t *testing.T){
+  oldPassword := MpdPassword
+  defer func(){ MpdPassword = oldPassword }()
+  MpdPassword = "secret"
+
+  backend, err := NewBackend(models.Room{Backend: models.BackendMpd, MpdAddress: "localhost:6600"})
+  if err != nil{
+    t.Fatal(err)
+  }
+  if b := backend.(*Mpd); b.Password != "secret"{
+    t.Errorf("Password = %q, want the configured one", b.Password)
+  }
+}
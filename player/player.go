@@ -0,0 +1,278 @@
+package player
+
+import(
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/models"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "strconv"
+  "sync"
+  "time"
+)
+
+var PollInterval = time.Second*2
+
+// How long to give a device to pick up a track before deciding it has ended
+var StartGrace = time.Second*5
+
+var players = make(map[uint]*Player)
+var playersLock sync.Mutex
+
+// Start preloading the next track this long before the current one ends
+var PreloadWindow = time.Second*10
+
+type Player struct{
+  RoomID      uint
+  // Overrides the configured Spotify API url, for pointing at a fake
+  ApiUrl      string
+
+  stop        chan bool
+  current     uint
+  enqueued    uint
+  startedAt   time.Time
+  backend     Backend
+  backendKey  string
+  autoFilledAt  time.Time
+}
+
+func NewPlayer(roomId uint) *Player{
+  return &Player{
+    RoomID: roomId,
+    stop: make(chan bool),
+  }
+}
+
+func Start(room models.Room){
+  playersLock.Lock()
+  defer playersLock.Unlock()
+
+  if _, running := players[room.ID]; running{
+    return
+  }
+
+  p := NewPlayer(room.ID)
+  players[room.ID] = p
+  go p.Run()
+
+  log.WithFields(log.Fields{
+    "roomId": room.ID,
+    "backend": room.BackendName(),
+  }).Info("Started room player")
+}
+
+func Stop(room models.Room){
+  playersLock.Lock()
+  defer playersLock.Unlock()
+
+  p, running := players[room.ID]
+  if !running{
+    return
+  }
+
+  close(p.stop)
+  delete(players, room.ID)
+
+  log.WithFields(log.Fields{
+    "roomId": room.ID,
+  }).Info("Stopped room player")
+}
+
+func Running(room models.Room) bool{
+  playersLock.Lock()
+  defer playersLock.Unlock()
+
+  _, running := players[room.ID]
+  return running
+}
+
+func StartAll(){
+  d := db.Db()
+  var rooms []models.Room
+  d.Where("active = ?", true).Find(&rooms)
+
+  for _,room := range rooms{
+    if room.PlayerConfigured(){
+      Start(room)
+    }
+  }
+}
+
+func (p *Player) Run(){
+  ticker := time.NewTicker(PollInterval)
+  defer ticker.Stop()
+
+  for{
+    select{
+    case <-p.stop:
+      return
+    case <-ticker.C:
+      err := p.Tick()
+      if err != nil{
+        log.WithFields(log.Fields{
+          "roomId": p.RoomID,
+          "error": err,
+        }).Warning("Player error")
+      }
+    }
+  }
+}
+
+func (p *Player) backendFor(room models.Room) (Backend, error){
+  /*
+    Backends can hold state about what has been enqueued so are kept between
+    ticks unless the room's playback settings change
+  */
+  key := fmt.Sprintf("%s|%s|%s", room.BackendName(), room.DeviceID, room.MpdAddress)
+  if p.backend != nil && p.backendKey == key{
+    return p.backend, nil
+  }
+
+  backend, err := NewBackend(room)
+  if err != nil{
+    return nil, err
+  }
+  if spotifyBackend, isSpotify := backend.(*SpotifyConnect); isSpotify{
+    spotifyBackend.ApiUrl = p.ApiUrl
+  }
+
+  p.backend = backend
+  p.backendKey = key
+  p.current = 0
+  p.enqueued = 0
+  return backend, nil
+}
+
+func (p *Player) Tick() error{
+  /*
+    Reconcile the backend with the room's now playing track, the database is
+    the source of truth so skips from the web UI are picked up here
+  */
+  room := models.Room{}
+  room.ById(strconv.FormatUint(uint64(p.RoomID), 10))
+  if !room.PlayerConfigured(){
+    return nil
+  }
+
+  backend, err := p.backendFor(room)
+  if err != nil{
+    return err
+  }
+
+  err = p.autoFill(room, backend)
+  if err != nil{
+    log.WithFields(log.Fields{
+      "roomId": room.ID,
+      "error": err,
+    }).Warning("Auto-DJ error")
+  }
+
+  current, playing := room.NowPlaying()
+  if !playing{
+    current, playing = room.Advance(false)
+    if !playing{
+      if p.current != 0{
+        // What we were playing was skipped and there is nothing to follow it
+        p.current = 0
+        return backend.Skip()
+      }
+      return nil
+    }
+  }
+
+  if current.ID != p.current{
+    return p.play(backend, room, current)
+  }
+
+  if time.Since(p.startedAt) < StartGrace{
+    return nil
+  }
+
+  status, err := backend.Status()
+  if err != nil{
+    return err
+  }
+
+  next, hasNext := room.NextEntry()
+  if hasNext && p.enqueued != next.ID && backend.CanPlay(next) && status.Playing && status.TrackUri == current.TrackUri && status.Remaining() < PreloadWindow{
+    err := backend.Enqueue(next)
+    if err != nil{
+      return err
+    }
+    p.enqueued = next.ID
+    return nil
+  }
+
+  preloaded := hasNext && p.enqueued == next.ID
+  if !ended(status, current, preloaded){
+    return nil
+  }
+
+  log.WithFields(log.Fields{
+    "roomId": room.ID,
+    "entryId": current.ID,
+  }).Debug("Track finished")
+
+  if preloaded && status.TrackUri == next.TrackUri{
+    // The backend has already moved on to the track we gave it
+    room.Advance(false)
+    p.current = next.ID
+    p.startedAt = time.Now()
+    p.enqueued = 0
+    return nil
+  }
+
+  next, found := room.Advance(false)
+  if !found{
+    p.current = 0
+    return nil
+  }
+  return p.play(backend, room, next)
+}
+
+func (p *Player) play(backend Backend, room models.Room, e models.QueueEntry) error{
+  if !backend.CanPlay(e){
+    log.WithFields(log.Fields{
+      "roomId": room.ID,
+      "entryId": e.ID,
+      "track": e.TrackUri,
+      "backend": room.BackendName(),
+    }).Warning("Backend cannot play track, moving on")
+
+    // The next tick will pick up whatever follows
+    room.AdvanceUnplayable()
+    p.current = 0
+    return nil
+  }
+
+  err := backend.Play(e)
+  if err != nil{
+    return err
+  }
+
+  p.current = e.ID
+  p.enqueued = 0
+  p.startedAt = time.Now()
+
+  log.WithFields(log.Fields{
+    "roomId": room.ID,
+    "entryId": e.ID,
+    "track": e.TrackUri,
+    "backend": room.BackendName(),
+  }).Info("Started playback")
+  return nil
+}
+
+func ended(status Status, current models.QueueEntry, preloaded bool) bool{
+  // Something else is playing, or playback stopped entirely
+  if status.TrackUri != current.TrackUri{
+    return true
+  }
+
+  // Players rewind to the start and stop once a track is finished
+  if !status.Playing && status.Position == 0{
+    return true
+  }
+
+  // When the next track is preloaded let the backend move on by itself
+  return !preloaded && status.Playing && status.Remaining() < time.Second
+}
@@ -0,0 +1,245 @@
+package player
+
+import(
+  "github.com/samarudge/jukebox/auth"
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/spotify"
+  "github.com/samarudge/jukebox/testdb"
+  "encoding/json"
+  "net/http"
+  "net/http/httptest"
+  "reflect"
+  "strconv"
+  "strings"
+  "sync"
+  "testing"
+  "time"
+)
+
+const testDevice = "device-1"
+
+// A Spotify Connect device, playing whatever it was last told to
+type fakeSpotify struct{
+  sync.Mutex
+  server  *httptest.Server
+  state   spotify.PlaybackState
+  played  []string
+  queued  []string
+}
+
+func newFakeSpotify(t *testing.T) *fakeSpotify{
+  f := &fakeSpotify{}
+  f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+    f.Lock()
+    defer f.Unlock()
+
+    if r.Header.Get("Authorization") != "Bearer test-token"{
+      w.WriteHeader(401)
+      return
+    }
+
+    switch r.Method + " " + r.URL.Path{
+    case "GET /me/player":
+      json.NewEncoder(w).Encode(f.state)
+      return
+    case "PUT /me/player/play":
+      var body struct{
+        Uris  []string  `json:"uris"`
+      }
+      json.NewDecoder(r.Body).Decode(&body)
+      if len(body.Uris) > 0{
+        f.played = append(f.played, body.Uris[0])
+        f.state.Item = spotify.Track{Uri: body.Uris[0], DurationMs: 200000}
+        f.state.ProgressMs = 0
+      }
+      f.state.IsPlaying = true
+    case "PUT /me/player/pause":
+      f.state.IsPlaying = false
+    case "POST /me/player/queue":
+      f.queued = append(f.queued, r.URL.Query().Get("uri"))
+    default:
+      t.Errorf("Unexpected call %s %s", r.Method, r.URL.Path)
+      http.NotFound(w, r)
+      return
+    }
+
+    if r.URL.Query().Get("device_id") != testDevice{
+      t.Errorf("%s %s went to device %q", r.Method, r.URL.Path, r.URL.Query().Get("device_id"))
+    }
+    w.WriteHeader(204)
+  }))
+  return f
+}
+
+func (f *fakeSpotify) progress(uri string, ms int, playing bool){
+  f.Lock()
+  defer f.Unlock()
+  f.state.Item = spotify.Track{Uri: uri, DurationMs: 200000}
+  f.state.ProgressMs = ms
+  f.state.IsPlaying = playing
+}
+
+func (f *fakeSpotify) calls() ([]string, []string){
+  f.Lock()
+  defer f.Unlock()
+  return append([]string{}, f.played...), append([]string{}, f.queued...)
+}
+
+func playerTestRoom(t *testing.T) (models.Room, func()){
+  cleanup := testdb.Open(t)
+  d := db.Db()
+
+  auth.Providers["spotify"] = auth.NewSpotify(auth.BaseProvider{ClientId: "test"}, nil)
+  u := testdb.User(t, models.Oauth2{
+    Provider: "spotify",
+    AccessToken: "test-token",
+    Scopes: strings.Join(auth.SpotifyPlaybackScopes, " "),
+    TokenExpires: time.Now().UTC().Add(time.Hour),
+  })
+  s := models.Spotify{Oauth2ID: u.Oauth2ID, SystemAccount: true}
+  d.Create(&s)
+
+  room := testdb.Room(t, u, "Office", "spotify:track:first", "spotify:track:second")
+  room.DeviceID = testDevice
+  d.Save(&room)
+  return room, cleanup
+}
+
+func playerTestSettings(startGrace time.Duration, preloadWindow time.Duration) func(){
+  oldGrace, oldWindow := StartGrace, PreloadWindow
+  StartGrace, PreloadWindow = startGrace, preloadWindow
+  return func(){ StartGrace, PreloadWindow = oldGrace, oldWindow }
+}
+
+func nowPlaying(room models.Room) string{
+  current, playing := room.NowPlaying()
+  if !playing{
+    return ""
+  }
+  return current.TrackUri
+}
+
+func TestPlayerAdvancesWhenTrackEnds(t *testing.T){
+  defer playerTestSettings(0, 0)()
+  room, cleanup := playerTestRoom(t)
+  defer cleanup()
+  f := newFakeSpotify(t)
+  defer f.server.Close()
+
+  p := NewPlayer(room.ID)
+  p.ApiUrl = f.server.URL
+
+  // Nothing playing, the head of the queue starts
+  if err := p.Tick(); err != nil{
+    t.Fatal(err)
+  }
+  if uri := nowPlaying(room); uri != "spotify:track:first"{
+    t.Fatalf("Now playing %q after the first tick", uri)
+  }
+
+  // Half way through nothing changes
+  f.progress("spotify:track:first", 100000, true)
+  if err := p.Tick(); err != nil{
+    t.Fatal(err)
+  }
+
+  // The device rewinds and stops at the end of the track
+  f.progress("spotify:track:first", 0, false)
+  if err := p.Tick(); err != nil{
+    t.Fatal(err)
+  }
+  if uri := nowPlaying(room); uri != "spotify:track:second"{
+    t.Fatalf("Now playing %q after the first track ended", uri)
+  }
+
+  // And once the last track ends the room is left with nothing playing
+  f.progress("spotify:track:second", 199500, true)
+  if err := p.Tick(); err != nil{
+    t.Fatal(err)
+  }
+  if uri := nowPlaying(room); uri != ""{
+    t.Errorf("Still playing %q with an empty queue", uri)
+  }
+
+  played, queued := f.calls()
+  if !reflect.DeepEqual(played, []string{"spotify:track:first", "spotify:track:second"}){
+    t.Errorf("Played %q", played)
+  }
+  if len(queued) != 0{
+    t.Errorf("Enqueued %q with preloading off", queued)
+  }
+  if len(room.History(10)) != 2{
+    t.Errorf("%d tracks in the room's history, want 2", len(room.History(10)))
+  }
+}
+
+func TestPlayerPreloadsNextTrack(t *testing.T){
+  defer playerTestSettings(0, 10*time.Second)()
+  room, cleanup := playerTestRoom(t)
+  defer cleanup()
+  f := newFakeSpotify(t)
+  defer f.server.Close()
+
+  p := NewPlayer(room.ID)
+  p.ApiUrl = f.server.URL
+
+  if err := p.Tick(); err != nil{
+    t.Fatal(err)
+  }
+
+  // Close to the end the next track is handed to the device
+  f.progress("spotify:track:first", 195000, true)
+  if err := p.Tick(); err != nil{
+    t.Fatal(err)
+  }
+  if _, queued := f.calls(); !reflect.DeepEqual(queued, []string{"spotify:track:second"}){
+    t.Fatalf("Enqueued %q", queued)
+  }
+
+  // The device moves on by itself, the room follows without restarting playback
+  f.progress("spotify:track:second", 1000, true)
+  if err := p.Tick(); err != nil{
+    t.Fatal(err)
+  }
+  if uri := nowPlaying(room); uri != "spotify:track:second"{
+    t.Errorf("Now playing %q after the device moved on", uri)
+  }
+  if played, _ := f.calls(); !reflect.DeepEqual(played, []string{"spotify:track:first"}){
+    t.Errorf("Played %q, the second track should have followed from the device's queue", played)
+  }
+}
+
+func TestPlayerMovesPastUnplayableTrack(t *testing.T){
+  defer playerTestSettings(0, 0)()
+  room, cleanup := playerTestRoom(t)
+  defer cleanup()
+  d := db.Db()
+  f := newFakeSpotify(t)
+  defer f.server.Close()
+
+  // A library track queued before the room moved to Spotify
+  unplayable, _ := room.NextEntry()
+  unplayable.TrackUri = "local:track:1"
+  d.Save(&unplayable)
+
+  p := NewPlayer(room.ID)
+  p.ApiUrl = f.server.URL
+  for i := 0; i < 2; i++{
+    if err := p.Tick(); err != nil{
+      t.Fatal(err)
+    }
+  }
+
+  if uri := nowPlaying(room); uri != "spotify:track:second"{
+    t.Fatalf("Now playing %q", uri)
+  }
+  finished := models.QueueEntry{}
+  finished.ById(strconv.FormatUint(uint64(unplayable.ID), 10))
+  if finished.Status != models.QueueStatusUnplayable{
+    t.Errorf("Unplayable track finished as %s", finished.Status)
+  }
+  if len(room.History(10)) != 0{
+    t.Error("The unplayable track was recorded as played or skipped")
+  }
+}
@@ -0,0 +1,119 @@
+package player
+
+import(
+  "github.com/samarudge/jukebox/models"
+  "github.com/samarudge/jukebox/spotify"
+  "fmt"
+  "strings"
+  "time"
+)
+
+type SpotifyConnect struct{
+  DeviceID  string
+  // Overrides the configured Spotify API url, for pointing at a fake
+  ApiUrl    string
+
+  enqueued  bool
+}
+
+func (b *SpotifyConnect) client() (*spotify.Client, error){
+  s := models.Spotify{}
+  s.LoadSystem()
+  if !s.Exists(){
+    return nil, fmt.Errorf("System Spotify account not configured")
+  }
+  if !s.CanControlPlayback(){
+    return nil, fmt.Errorf("System Spotify account was linked before playback control was added, re-link it from the admin page")
+  }
+
+  client := s.Client()
+  if b.ApiUrl != ""{
+    client.BaseUrl = b.ApiUrl
+  }
+  return client, nil
+}
+
+func (b *SpotifyConnect) CanPlay(e models.QueueEntry) bool{
+  return strings.HasPrefix(e.TrackUri, "spotify:")
+}
+
+func (b *SpotifyConnect) Play(e models.QueueEntry) error{
+  client, err := b.client()
+  if err != nil{
+    return err
+  }
+
+  b.enqueued = false
+  return client.Play(b.DeviceID, []string{e.TrackUri})
+}
+
+func (b *SpotifyConnect) Pause() error{
+  client, err := b.client()
+  if err != nil{
+    return err
+  }
+  return client.Pause(b.DeviceID)
+}
+
+func (b *SpotifyConnect) Resume() error{
+  client, err := b.client()
+  if err != nil{
+    return err
+  }
+  return client.Resume(b.DeviceID)
+}
+
+func (b *SpotifyConnect) Skip() error{
+  client, err := b.client()
+  if err != nil{
+    return err
+  }
+
+  // Without anything enqueued Spotify would start playing its own suggestions
+  if !b.enqueued{
+    return client.Pause(b.DeviceID)
+  }
+
+  b.enqueued = false
+  return client.Next(b.DeviceID)
+}
+
+func (b *SpotifyConnect) SetVolume(percent int) error{
+  client, err := b.client()
+  if err != nil{
+    return err
+  }
+  return client.SetVolume(b.DeviceID, percent)
+}
+
+func (b *SpotifyConnect) Status() (Status, error){
+  client, err := b.client()
+  if err != nil{
+    return Status{}, err
+  }
+
+  state, err := client.PlaybackState()
+  if err != nil{
+    return Status{}, err
+  }
+
+  return Status{
+    Playing: state.IsPlaying,
+    TrackUri: state.Item.Uri,
+    Position: time.Duration(state.ProgressMs)*time.Millisecond,
+    Duration: time.Duration(state.Item.DurationMs)*time.Millisecond,
+  }, nil
+}
+
+func (b *SpotifyConnect) Enqueue(e models.QueueEntry) error{
+  client, err := b.client()
+  if err != nil{
+    return err
+  }
+
+  err = client.AddToQueue(b.DeviceID, e.TrackUri)
+  if err == nil{
+    b.enqueued = true
+  }
+  return err
+}
@@ -0,0 +1,287 @@
+package player
+
+import(
+  "github.com/samarudge/jukebox/library"
+  "github.com/samarudge/jukebox/models"
+  log "github.com/Sirupsen/logrus"
+  "fmt"
+  "io"
+  "os"
+  "sync"
+  "time"
+)
+
+// Bytes of audio between ICY metadata blocks
+const IcyMetaInt = 16000
+
+const streamTick = time.Millisecond*100
+
+// Rate used for silence and for files with an unknown duration, 128kbps
+const defaultByteRate = 16000
+
+// A 128kbps 44.1kHz MPEG-1 Layer III frame with empty side info decodes as silence
+var silentFrame = append([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 413)...)
+
+var stations = make(map[uint]*Station)
+var stationsLock sync.Mutex
+
+type Listener struct{
+  Audio     chan []byte
+  closed    bool
+}
+
+type Station struct{
+  RoomID    uint
+
+  lock      sync.Mutex
+  listeners map[*Listener]bool
+  current   models.QueueEntry
+  next      models.QueueEntry
+  file      *os.File
+  byteRate  int
+  sent      int64
+  size      int64
+  playing   bool
+  paused    bool
+}
+
+func StationFor(roomId uint) *Station{
+  stationsLock.Lock()
+  defer stationsLock.Unlock()
+
+  s, found := stations[roomId]
+  if !found{
+    s = &Station{
+      RoomID: roomId,
+      listeners: make(map[*Listener]bool),
+    }
+    stations[roomId] = s
+    go s.run()
+  }
+  return s
+}
+
+func (s *Station) Listen() *Listener{
+  s.lock.Lock()
+  defer s.lock.Unlock()
+
+  l := &Listener{
+    Audio: make(chan []byte, 64),
+  }
+  s.listeners[l] = true
+  return l
+}
+
+func (s *Station) Unlisten(l *Listener){
+  s.lock.Lock()
+  defer s.lock.Unlock()
+  s.closeListener(l)
+}
+
+func (s *Station) closeListener(l *Listener){
+  if !l.closed{
+    l.closed = true
+    close(l.Audio)
+  }
+  delete(s.listeners, l)
+}
+
+func (s *Station) Listeners() int{
+  s.lock.Lock()
+  defer s.lock.Unlock()
+  return len(s.listeners)
+}
+
+func (s *Station) Title() string{
+  s.lock.Lock()
+  defer s.lock.Unlock()
+
+  if !s.playing{
+    return ""
+  }
+  if s.current.Artist == ""{
+    return s.current.Title
+  }
+  return fmt.Sprintf("%s - %s", s.current.Artist, s.current.Title)
+}
+
+func (s *Station) open(e models.QueueEntry) error{
+  t := models.LibraryTrack{}
+  if !t.ByUri(e.TrackUri){
+    return fmt.Errorf("Track %s is not in the library", e.TrackUri)
+  }
+
+  path := library.FilePath(t)
+  f, err := os.Open(path)
+  if err != nil{
+    return err
+  }
+  info, err := f.Stat()
+  if err != nil{
+    f.Close()
+    return err
+  }
+
+  start := library.AudioStart(path)
+  f.Seek(start, 0)
+
+  if s.file != nil{
+    s.file.Close()
+  }
+  s.file = f
+  s.current = e
+  s.next = models.QueueEntry{}
+  s.sent = 0
+  s.size = info.Size() - start
+  s.byteRate = defaultByteRate
+  if t.DurationMs > 0{
+    s.byteRate = int(s.size*1000/int64(t.DurationMs))
+  }
+  s.playing = true
+  s.paused = false
+  return nil
+}
+
+func (s *Station) stop(){
+  if s.file != nil{
+    s.file.Close()
+    s.file = nil
+  }
+  s.playing = false
+  s.sent = 0
+}
+
+func (s *Station) advance(){
+  /*
+    Move on to the enqueued track if there is one, otherwise stop
+  */
+  if s.next.ID == 0{
+    s.stop()
+    return
+  }
+
+  err := s.open(s.next)
+  if err != nil{
+    log.WithFields(log.Fields{
+      "roomId": s.RoomID,
+      "error": err,
+    }).Warning("Could not open next stream track")
+    s.stop()
+  }
+}
+
+func (s *Station) chunk() []byte{
+  if !s.playing || s.paused{
+    var silence []byte
+    for len(silence) < defaultByteRate/int(time.Second/streamTick){
+      silence = append(silence, silentFrame...)
+    }
+    return silence
+  }
+
+  buf := make([]byte, s.byteRate/int(time.Second/streamTick))
+  n, err := io.ReadFull(s.file, buf)
+  s.sent += int64(n)
+  if err != nil{
+    s.advance()
+  }
+  return buf[:n]
+}
+
+func (s *Station) run(){
+  /*
+    Keep time whether or not anyone is listening so the player sees tracks
+    finish at the right moment
+  */
+  ticker := time.NewTicker(streamTick)
+  defer ticker.Stop()
+
+  for range ticker.C{
+    s.lock.Lock()
+    data := s.chunk()
+    for l := range s.listeners{
+      select{
+      case l.Audio <- data:
+      default:
+        // Listener can't keep up, drop them rather than stall everyone
+        s.closeListener(l)
+      }
+    }
+    s.lock.Unlock()
+  }
+}
+
+// Stream is the backend the room player drives
+type Stream struct{
+  RoomID  uint
+}
+
+func (b *Stream) station() *Station{
+  return StationFor(b.RoomID)
+}
+
+func (b *Stream) CanPlay(e models.QueueEntry) bool{
+  // Tracks are concatenated into a single MP3 stream
+  t := models.LibraryTrack{}
+  return t.ByUri(e.TrackUri) && t.Format == "mp3"
+}
+
+func (b *Stream) Play(e models.QueueEntry) error{
+  s := b.station()
+  s.lock.Lock()
+  defer s.lock.Unlock()
+  return s.open(e)
+}
+
+func (b *Stream) Pause() error{
+  s := b.station()
+  s.lock.Lock()
+  defer s.lock.Unlock()
+  s.paused = true
+  return nil
+}
+
+func (b *Stream) Resume() error{
+  s := b.station()
+  s.lock.Lock()
+  defer s.lock.Unlock()
+  s.paused = false
+  return nil
+}
+
+func (b *Stream) Skip() error{
+  s := b.station()
+  s.lock.Lock()
+  defer s.lock.Unlock()
+  s.advance()
+  return nil
+}
+
+func (b *Stream) SetVolume(percent int) error{
+  return fmt.Errorf("Stream volume is controlled by each listener")
+}
+
+func (b *Stream) Status() (Status, error){
+  s := b.station()
+  s.lock.Lock()
+  defer s.lock.Unlock()
+
+  if !s.playing || s.byteRate == 0{
+    return Status{}, nil
+  }
+
+  return Status{
+    Playing: !s.paused,
+    TrackUri: s.current.TrackUri,
+    Position: time.Duration(s.sent*int64(time.Second)/int64(s.byteRate)),
+    Duration: time.Duration(s.size*int64(time.Second)/int64(s.byteRate)),
+  }, nil
+}
+
+func (b *Stream) Enqueue(e models.QueueEntry) error{
+  s := b.station()
+  s.lock.Lock()
+  defer s.lock.Unlock()
+  s.next = e
+  return nil
+}
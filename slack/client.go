@@ -0,0 +1,120 @@
+package slack
+
+import(
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "net/url"
+  "strings"
+  "sync"
+  "time"
+  log "github.com/Sirupsen/logrus"
+)
+
+// Set from config, Slack requests are refused until both are
+var SigningSecret string
+var BotToken string
+
+// Overridden from config so the client can be pointed at a local fake
+var ApiUrl = "https://slack.com/api"
+
+// Slash commands and buttons have to answer quickly, so nothing waits long on Slack
+var HttpClient = &http.Client{Timeout: 5*time.Second}
+
+func Configured() bool{
+  return len(SigningSecret) > 0 && len(BotToken) > 0
+}
+
+// Slack users don't change email often, saves a users.info call per command
+var emails = map[string]string{}
+var emailsLock sync.Mutex
+
+type Client struct{
+  BaseUrl string
+  Token   string
+  Http    *http.Client
+}
+
+func NewClient() *Client{
+  return &Client{
+    BaseUrl: strings.TrimRight(ApiUrl, "/"),
+    Token: BotToken,
+    Http: HttpClient,
+  }
+}
+
+func (c *Client) get(method string, query url.Values, out interface{}) error{
+  req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s?%s", c.BaseUrl, method, query.Encode()), nil)
+  if err != nil{
+    return err
+  }
+  req.Header.Set("Authorization", "Bearer " + c.Token)
+
+  rsp, err := c.Http.Do(req)
+  if err != nil{
+    return err
+  }
+
+  log.WithFields(log.Fields{
+    "call": method,
+    "status": rsp.StatusCode,
+  }).Debug("Slack API")
+
+  defer rsp.Body.Close()
+  responseRaw, _ := ioutil.ReadAll(rsp.Body)
+
+  if rsp.StatusCode < 200 || rsp.StatusCode > 299{
+    return fmt.Errorf("Slack API error: %d %s", rsp.StatusCode, responseRaw)
+  }
+
+  // Slack answers 200 for most errors and sets ok instead
+  var status struct{
+    Ok    bool    `json:"ok"`
+    Error string  `json:"error"`
+  }
+  if err := json.Unmarshal(responseRaw, &status); err != nil{
+    return fmt.Errorf("Could not decode JSON: %s", err)
+  }
+  if !status.Ok{
+    return fmt.Errorf("Slack API error: %s", status.Error)
+  }
+
+  if err := json.Unmarshal(responseRaw, out); err != nil{
+    return fmt.Errorf("Could not decode JSON: %s", err)
+  }
+  return nil
+}
+
+func (c *Client) UserEmail(userId string) (string, error){
+  // Needs the users:read.email scope
+  emailsLock.Lock()
+  email, found := emails[userId]
+  emailsLock.Unlock()
+  if found{
+    return email, nil
+  }
+
+  var rsp struct{
+    User  struct{
+      Profile struct{
+        Email string  `json:"email"`
+      } `json:"profile"`
+    } `json:"user"`
+  }
+  query := url.Values{}
+  query.Set("user", userId)
+  if err := c.get("users.info", query, &rsp); err != nil{
+    return "", err
+  }
+
+  email = rsp.User.Profile.Email
+  if len(email) == 0{
+    return "", fmt.Errorf("Slack has no email address for this user")
+  }
+
+  emailsLock.Lock()
+  emails[userId] = email
+  emailsLock.Unlock()
+  return email, nil
+}
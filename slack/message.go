@@ -0,0 +1,135 @@
+package slack
+
+import(
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "net/url"
+  "strings"
+)
+
+const(
+  ResponseEphemeral = "ephemeral"
+  ResponseInChannel = "in_channel"
+)
+
+// Sent as a form by Slack when someone uses a slash command
+type Command struct{
+  Command     string
+  Text        string
+  UserId      string
+  UserName    string
+  TeamId      string
+  ChannelId   string
+  ResponseUrl string
+}
+
+func ParseCommand(form url.Values) Command{
+  return Command{
+    Command: form.Get("command"),
+    Text: strings.TrimSpace(form.Get("text")),
+    UserId: form.Get("user_id"),
+    UserName: form.Get("user_name"),
+    TeamId: form.Get("team_id"),
+    ChannelId: form.Get("channel_id"),
+    ResponseUrl: form.Get("response_url"),
+  }
+}
+
+// Sent as JSON in the payload field when someone presses a button
+type Interaction struct{
+  Type        string    `json:"type"`
+  User        struct{
+    Id        string    `json:"id"`
+    Username  string    `json:"username"`
+  } `json:"user"`
+  Actions     []Action  `json:"actions"`
+  ResponseUrl string    `json:"response_url"`
+}
+
+type Action struct{
+  ActionId  string  `json:"action_id"`
+  BlockId   string  `json:"block_id"`
+  Value     string  `json:"value"`
+}
+
+func ParseInteraction(form url.Values) (Interaction, error){
+  i := Interaction{}
+  if err := json.Unmarshal([]byte(form.Get("payload")), &i); err != nil{
+    return i, fmt.Errorf("Could not decode interaction payload: %s", err)
+  }
+  return i, nil
+}
+
+type Text struct{
+  Type  string  `json:"type"`
+  Text  string  `json:"text"`
+}
+
+type Element struct{
+  Type      string  `json:"type"`
+  Text      *Text   `json:"text,omitempty"`
+  ActionId  string  `json:"action_id,omitempty"`
+  Value     string  `json:"value,omitempty"`
+}
+
+type Block struct{
+  Type      string    `json:"type"`
+  BlockId   string    `json:"block_id,omitempty"`
+  Text      *Text     `json:"text,omitempty"`
+  Accessory *Element  `json:"accessory,omitempty"`
+}
+
+type Message struct{
+  ResponseType    string  `json:"response_type,omitempty"`
+  ReplaceOriginal bool    `json:"replace_original,omitempty"`
+  Text            string  `json:"text"`
+  Blocks          []Block `json:"blocks,omitempty"`
+}
+
+func Ephemeral(text string) Message{
+  return Message{
+    ResponseType: ResponseEphemeral,
+    Text: text,
+  }
+}
+
+func Markdown(text string) *Text{
+  return &Text{Type: "mrkdwn", Text: text}
+}
+
+func Button(label string, actionId string, value string) *Element{
+  return &Element{
+    Type: "button",
+    Text: &Text{Type: "plain_text", Text: label},
+    ActionId: actionId,
+    Value: value,
+  }
+}
+
+func Escape(text string) string{
+  // The only characters Slack needs escaped in message text
+  return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+func Respond(responseUrl string, m Message) error{
+  /*
+    Commands and button presses are answered through the response URL, the
+    response to the request itself is ignored
+  */
+  body, err := json.Marshal(m)
+  if err != nil{
+    return err
+  }
+
+  rsp, err := HttpClient.Post(responseUrl, "application/json", bytes.NewReader(body))
+  if err != nil{
+    return err
+  }
+  defer rsp.Body.Close()
+
+  if rsp.StatusCode < 200 || rsp.StatusCode > 299{
+    return fmt.Errorf("Slack response URL error: %d", rsp.StatusCode)
+  }
+  return nil
+}
@@ -0,0 +1,44 @@
+package slack
+
+import(
+  "crypto/hmac"
+  "crypto/sha256"
+  "encoding/hex"
+  "fmt"
+  "strconv"
+  "time"
+)
+
+// Requests signed longer ago than this are refused so they can't be replayed
+const MaxRequestAge = 5*time.Minute
+
+func Signature(secret string, timestamp string, body []byte) string{
+  mac := hmac.New(sha256.New, []byte(secret))
+  fmt.Fprintf(mac, "v0:%s:", timestamp)
+  mac.Write(body)
+  return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(secret string, timestamp string, body []byte, signature string, now time.Time) error{
+  /*
+    Checks the X-Slack-Signature header, now is passed in so a recorded
+    request can be checked against the time it was recorded
+  */
+  if len(secret) == 0{
+    return fmt.Errorf("No Slack signing secret is configured")
+  }
+
+  sent, err := strconv.ParseInt(timestamp, 10, 64)
+  if err != nil{
+    return fmt.Errorf("Invalid request timestamp")
+  }
+  age := now.Sub(time.Unix(sent, 0))
+  if age > MaxRequestAge || age < -MaxRequestAge{
+    return fmt.Errorf("Request timestamp is too far from now")
+  }
+
+  if !hmac.Equal([]byte(Signature(secret, timestamp, body)), []byte(signature)){
+    return fmt.Errorf("Invalid request signature")
+  }
+  return nil
+}
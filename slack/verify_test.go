@@ -0,0 +1,54 @@
+package slack
+
+import(
+  "testing"
+  "time"
+)
+
+// The example request from Slack's "Verifying requests from Slack" docs
+const exampleSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+const exampleTimestamp = "1531420618"
+const exampleBody = "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+const exampleSignature = "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+
+var exampleTime = time.Unix(1531420618, 0)
+
+func TestSignature(t *testing.T){
+  if s := Signature(exampleSecret, exampleTimestamp, []byte(exampleBody)); s != exampleSignature{
+    t.Errorf("Signature = %s, want %s", s, exampleSignature)
+  }
+}
+
+func TestVerify(t *testing.T){
+  tests := []struct{
+    name      string
+    secret    string
+    timestamp string
+    body      string
+    signature string
+    now       time.Time
+    ok        bool
+  }{
+    {"example", exampleSecret, exampleTimestamp, exampleBody, exampleSignature, exampleTime, true},
+    {"a little later", exampleSecret, exampleTimestamp, exampleBody, exampleSignature, exampleTime.Add(MaxRequestAge - time.Second), true},
+    {"stale", exampleSecret, exampleTimestamp, exampleBody, exampleSignature, exampleTime.Add(MaxRequestAge + time.Second), false},
+    {"from the future", exampleSecret, exampleTimestamp, exampleBody, exampleSignature, exampleTime.Add(-MaxRequestAge - time.Second), false},
+    {"no secret", "", exampleTimestamp, exampleBody, exampleSignature, exampleTime, false},
+    {"wrong secret", "not the secret", exampleTimestamp, exampleBody, exampleSignature, exampleTime, false},
+    {"bad timestamp", exampleSecret, "yesterday", exampleBody, exampleSignature, exampleTime, false},
+    {"changed timestamp", exampleSecret, "1531420619", exampleBody, exampleSignature, exampleTime, false},
+    {"changed body", exampleSecret, exampleTimestamp, exampleBody + "&text=skip", exampleSignature, exampleTime, false},
+    {"no signature", exampleSecret, exampleTimestamp, exampleBody, "", exampleTime, false},
+    {"truncated signature", exampleSecret, exampleTimestamp, exampleBody, exampleSignature[:20], exampleTime, false},
+  }
+
+  for _, test := range tests{
+    err := Verify(test.secret, test.timestamp, []byte(test.body), test.signature, test.now)
+    if test.ok && err != nil{
+      t.Errorf("%s: unexpected error %s", test.name, err)
+    }
+    if !test.ok && err == nil{
+      t.Errorf("%s: verified, should have been refused", test.name)
+    }
+  }
+}
@@ -0,0 +1,93 @@
+package spotify
+
+import(
+  "bytes"
+  "io"
+  "fmt"
+  "net/http"
+  "net/url"
+  "io/ioutil"
+  "encoding/json"
+  "strings"
+  log "github.com/Sirupsen/logrus"
+)
+
+// Overridden from config so the client can be pointed at a local fake
+var ApiUrl = "https://api.spotify.com/v1"
+
+type Client struct{
+  BaseUrl   string
+  Http      *http.Client
+}
+
+func NewClient(httpClient *http.Client) *Client{
+  return &Client{
+    BaseUrl: strings.TrimRight(ApiUrl, "/"),
+    Http: httpClient,
+  }
+}
+
+func (c *Client) Url(path string, query url.Values) string{
+  u := fmt.Sprintf("%s%s", c.BaseUrl, path)
+  if query != nil && len(query) > 0{
+    u = fmt.Sprintf("%s?%s", u, query.Encode())
+  }
+  return u
+}
+
+func (c *Client) get(path string, query url.Values, out interface{}) error{
+  return c.do("GET", path, query, nil, out)
+}
+
+func (c *Client) put(path string, query url.Values, body interface{}) error{
+  return c.do("PUT", path, query, body, nil)
+}
+
+func (c *Client) post(path string, query url.Values, body interface{}) error{
+  return c.do("POST", path, query, body, nil)
+}
+
+func (c *Client) do(method string, path string, query url.Values, body interface{}, out interface{}) error{
+  var bodyReader io.Reader
+  if body != nil{
+    bodyRaw, err := json.Marshal(body)
+    if err != nil{
+      return err
+    }
+    bodyReader = bytes.NewReader(bodyRaw)
+  }
+
+  req, err := http.NewRequest(method, c.Url(path, query), bodyReader)
+  if err != nil{
+    return err
+  }
+  if body != nil{
+    req.Header.Set("Content-Type", "application/json")
+  }
+
+  rsp, err := c.Http.Do(req)
+  if err != nil{
+    return err
+  }
+
+  log.WithFields(log.Fields{
+    "call": rsp.Request.URL,
+    "status": rsp.StatusCode,
+  }).Debug("Spotify API")
+
+  defer rsp.Body.Close()
+  responseRaw, _ := ioutil.ReadAll(rsp.Body)
+
+  if rsp.StatusCode < 200 || rsp.StatusCode > 299{
+    return fmt.Errorf("Spotify API error: %d %s", rsp.StatusCode, responseRaw)
+  }
+
+  if out == nil || len(responseRaw) == 0{
+    return nil
+  }
+
+  if err := json.Unmarshal(responseRaw, out); err != nil{
+    return fmt.Errorf("Could not decode JSON: %s", err)
+  }
+  return nil
+}
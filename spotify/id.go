@@ -0,0 +1,28 @@
+package spotify
+
+import(
+  "strings"
+)
+
+const TrackUriPrefix = "spotify:track:"
+
+func ValidId(id string) bool{
+  // Spotify IDs are base62, anything else would end up in an API path
+  if len(id) == 0{
+    return false
+  }
+  for _, r := range id{
+    if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'){
+      return false
+    }
+  }
+  return true
+}
+
+func TrackId(uri string) (string, bool){
+  if !strings.HasPrefix(uri, TrackUriPrefix){
+    return "", false
+  }
+  id := strings.TrimPrefix(uri, TrackUriPrefix)
+  return id, ValidId(id)
+}
@@ -0,0 +1,55 @@
+package spotify
+
+import(
+  "fmt"
+  "net/url"
+  "strconv"
+)
+
+type Playlist struct{
+  Id      string  `json:"id"`
+  Uri     string  `json:"uri"`
+  Name    string  `json:"name"`
+  Images  []Image `json:"images"`
+  Owner   struct{
+    DisplayName string  `json:"display_name"`
+  } `json:"owner"`
+  Tracks  struct{
+    Total int `json:"total"`
+  } `json:"tracks"`
+}
+
+func (p Playlist) Thumbnail() string{
+  return thumbnail(p.Images)
+}
+
+type Playlists struct{
+  Paging
+  Items []Playlist  `json:"items"`
+}
+
+func pageQuery(limit int, offset int) url.Values{
+  q := url.Values{}
+  q.Set("limit", strconv.Itoa(limit))
+  q.Set("offset", strconv.Itoa(offset))
+  return q
+}
+
+func (c *Client) MyPlaylists(limit int, offset int) (Playlists, error){
+  result := Playlists{}
+  err := c.get("/me/playlists", pageQuery(limit, offset), &result)
+  return result, err
+}
+
+func (c *Client) Playlist(playlistId string) (Playlist, error){
+  result := Playlist{}
+  err := c.get(fmt.Sprintf("/playlists/%s", playlistId), nil, &result)
+  return result, err
+}
+
+func (c *Client) SavedTracks(limit int, offset int) (PlaylistTracks, error){
+  // Liked songs come back in the same shape as playlist tracks
+  result := PlaylistTracks{}
+  err := c.get("/me/tracks", pageQuery(limit, offset), &result)
+  return result, err
+}
@@ -0,0 +1,74 @@
+package spotify
+
+import(
+  "net/url"
+  "strconv"
+)
+
+type Device struct{
+  Id            string  `json:"id"`
+  Name          string  `json:"name"`
+  Type          string  `json:"type"`
+  IsActive      bool    `json:"is_active"`
+  VolumePercent int     `json:"volume_percent"`
+}
+
+type PlaybackState struct{
+  Device      Device  `json:"device"`
+  IsPlaying   bool    `json:"is_playing"`
+  ProgressMs  int     `json:"progress_ms"`
+  Item        Track   `json:"item"`
+}
+
+func deviceQuery(deviceId string) url.Values{
+  q := url.Values{}
+  if deviceId != ""{
+    q.Set("device_id", deviceId)
+  }
+  return q
+}
+
+func (c *Client) Devices() ([]Device, error){
+  var result struct{
+    Devices []Device  `json:"devices"`
+  }
+  err := c.get("/me/player/devices", nil, &result)
+  return result.Devices, err
+}
+
+func (c *Client) PlaybackState() (PlaybackState, error){
+  // Spotify returns an empty 204 when there is no active playback
+  state := PlaybackState{}
+  err := c.get("/me/player", nil, &state)
+  return state, err
+}
+
+func (c *Client) Play(deviceId string, uris []string) error{
+  return c.put("/me/player/play", deviceQuery(deviceId), map[string]interface{}{
+    "uris": uris,
+  })
+}
+
+func (c *Client) Pause(deviceId string) error{
+  return c.put("/me/player/pause", deviceQuery(deviceId), nil)
+}
+
+func (c *Client) Resume(deviceId string) error{
+  return c.put("/me/player/play", deviceQuery(deviceId), nil)
+}
+
+func (c *Client) Next(deviceId string) error{
+  return c.post("/me/player/next", deviceQuery(deviceId), nil)
+}
+
+func (c *Client) AddToQueue(deviceId string, uri string) error{
+  q := deviceQuery(deviceId)
+  q.Set("uri", uri)
+  return c.post("/me/player/queue", q, nil)
+}
+
+func (c *Client) SetVolume(deviceId string, percent int) error{
+  q := deviceQuery(deviceId)
+  q.Set("volume_percent", strconv.Itoa(percent))
+  return c.put("/me/player/volume", q, nil)
+}
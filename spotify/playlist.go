@@ -0,0 +1,115 @@
+package spotify
+
+import(
+  "fmt"
+  "net/url"
+  "strconv"
+  "strings"
+)
+
+type PlaylistTrack struct{
+  Track   Track   `json:"track"`
+}
+
+type PlaylistTracks struct{
+  Paging
+  Items []PlaylistTrack `json:"items"`
+}
+
+type Recommendations struct{
+  Tracks  []Track `json:"tracks"`
+}
+
+func PlaylistId(playlist string) string{
+  /*
+    Accepts a bare ID, a spotify:playlist: URI or an open.spotify.com link
+  */
+  playlist = strings.TrimSpace(playlist)
+  if strings.HasPrefix(playlist, "spotify:"){
+    parts := strings.Split(playlist, ":")
+    return parts[len(parts)-1]
+  }
+
+  u, err := url.Parse(playlist)
+  if err == nil && u.Host != ""{
+    parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+    return parts[len(parts)-1]
+  }
+  return playlist
+}
+
+func (c *Client) PlaylistTracks(playlistId string, limit int, offset int) (PlaylistTracks, error){
+  result := PlaylistTracks{}
+  if !ValidId(playlistId){
+    return result, fmt.Errorf("Invalid playlist ID %q", playlistId)
+  }
+  err := c.get(fmt.Sprintf("/playlists/%s/tracks", playlistId), pageQuery(limit, offset), &result)
+  return result, err
+}
+
+func (c *Client) Recommendations(seedTracks []string, limit int) ([]Track, error){
+  result := Recommendations{}
+
+  q := url.Values{}
+  q.Set("seed_tracks", strings.Join(seedTracks, ","))
+  q.Set("limit", strconv.Itoa(limit))
+
+  err := c.get("/recommendations", q, &result)
+  return result.Tracks, err
+}
+
+// The most tracks Spotify accepts in one request
+const playlistChunkSize = 100
+
+type Me struct{
+  Id          string  `json:"id"`
+  DisplayName string  `json:"display_name"`
+}
+
+func (c *Client) Me() (Me, error){
+  result := Me{}
+  err := c.get("/me", nil, &result)
+  return result, err
+}
+
+func (c *Client) CreatePlaylist(userId string, name string, description string) (Playlist, error){
+  result := Playlist{}
+  body := map[string]interface{}{
+    "name": name,
+    "description": description,
+    "public": false,
+  }
+  err := c.do("POST", fmt.Sprintf("/users/%s/playlists", url.QueryEscape(userId)), nil, body, &result)
+  return result, err
+}
+
+func (c *Client) ReplacePlaylistTracks(playlistId string, uris []string) error{
+  /*
+    Replacing is limited to 100 tracks, anything after that is appended
+  */
+  if !ValidId(playlistId){
+    return fmt.Errorf("Invalid playlist ID %q", playlistId)
+  }
+  path := fmt.Sprintf("/playlists/%s/tracks", playlistId)
+  first := uris
+  if len(first) > playlistChunkSize{
+    first = first[:playlistChunkSize]
+  }
+
+  err := c.put(path, nil, map[string][]string{"uris": first})
+  if err != nil{
+    return err
+  }
+
+  for i := playlistChunkSize; i < len(uris); i += playlistChunkSize{
+    end := i + playlistChunkSize
+    if end > len(uris){
+      end = len(uris)
+    }
+    err := c.post(path, nil, map[string][]string{"uris": uris[i:end]})
+    if err != nil{
+      return err
+    }
+  }
+  return nil
+}
@@ -0,0 +1,43 @@
+package spotify
+
+import(
+  "testing"
+)
+
+func TestPlaylistId(t *testing.T){
+  tests := []struct{
+    playlist  string
+    id        string
+    valid     bool
+  }{
+    {"37i9dQZF1DXcBWIGoYBM5M", "37i9dQZF1DXcBWIGoYBM5M", true},
+    {" spotify:playlist:37i9dQZF1DXcBWIGoYBM5M ", "37i9dQZF1DXcBWIGoYBM5M", true},
+    {"spotify:user:someone:playlist:37i9dQZF1DXcBWIGoYBM5M", "37i9dQZF1DXcBWIGoYBM5M", true},
+    {"https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M?si=1a2b3c", "37i9dQZF1DXcBWIGoYBM5M", true},
+    {"spotify:playlist:..%2F..%2Fme", "..%2F..%2Fme", false},
+    {"../../me/tracks", "../../me/tracks", false},
+    {"abc/tracks?limit=1", "abc/tracks?limit=1", false},
+    {"", "", false},
+  }
+
+  for _, test := range tests{
+    id := PlaylistId(test.playlist)
+    if id != test.id{
+      t.Errorf("PlaylistId(%q) = %q, want %q", test.playlist, id, test.id)
+    }
+    if ValidId(id) != test.valid{
+      t.Errorf("ValidId(%q) should be %t", id, test.valid)
+    }
+  }
+}
+
+func TestPlaylistTracksInvalidId(t *testing.T){
+  // Refused before any request is made, so no server is needed
+  client := NewClient(nil)
+  if _, err := client.PlaylistTracks("../me/tracks", 10, 0); err == nil{
+    t.Error("Fetched tracks for an invalid playlist ID")
+  }
+  if err := client.ReplacePlaylistTracks("abc?x=1", []string{"spotify:track:abc"}); err == nil{
+    t.Error("Replaced tracks for an invalid playlist ID")
+  }
+}
@@ -0,0 +1,127 @@
+package spotify
+
+import(
+  "fmt"
+  "net/url"
+  "strconv"
+  "strings"
+)
+
+type Image struct{
+  Url     string  `json:"url"`
+  Width   int     `json:"width"`
+  Height  int     `json:"height"`
+}
+
+type Artist struct{
+  Id      string  `json:"id"`
+  Uri     string  `json:"uri"`
+  Name    string  `json:"name"`
+  Images  []Image `json:"images"`
+}
+
+type Album struct{
+  Id      string    `json:"id"`
+  Uri     string    `json:"uri"`
+  Name    string    `json:"name"`
+  Artists []Artist  `json:"artists"`
+  Images  []Image   `json:"images"`
+}
+
+type Track struct{
+  Id          string    `json:"id"`
+  Uri         string    `json:"uri"`
+  Name        string    `json:"name"`
+  DurationMs  int       `json:"duration_ms"`
+  Explicit    bool      `json:"explicit"`
+  Artists     []Artist  `json:"artists"`
+  Album       Album     `json:"album"`
+}
+
+type Paging struct{
+  Total   int `json:"total"`
+  Limit   int `json:"limit"`
+  Offset  int `json:"offset"`
+}
+
+func (p Paging) HasNext() bool{
+  return p.Offset + p.Limit < p.Total
+}
+
+type SearchResult struct{
+  Tracks  struct{
+    Paging
+    Items []Track   `json:"items"`
+  } `json:"tracks"`
+  Albums  struct{
+    Paging
+    Items []Album   `json:"items"`
+  } `json:"albums"`
+  Artists struct{
+    Paging
+    Items []Artist  `json:"items"`
+  } `json:"artists"`
+}
+
+func (r SearchResult) HasNext() bool{
+  return r.Tracks.HasNext() || r.Albums.HasNext() || r.Artists.HasNext()
+}
+
+func (r SearchResult) Empty() bool{
+  return len(r.Tracks.Items) == 0 && len(r.Albums.Items) == 0 && len(r.Artists.Items) == 0
+}
+
+func artistNames(artists []Artist) string{
+  var names []string
+  for _,a := range artists{
+    names = append(names, a.Name)
+  }
+  return strings.Join(names, ", ")
+}
+
+// Spotify lists images largest first
+func thumbnail(images []Image) string{
+  if len(images) == 0{
+    return ""
+  }
+  return images[len(images)-1].Url
+}
+
+func (a Artist) Thumbnail() string{
+  return thumbnail(a.Images)
+}
+
+func (a Album) Thumbnail() string{
+  return thumbnail(a.Images)
+}
+
+func (t Track) ArtistNames() string{
+  return artistNames(t.Artists)
+}
+
+func (t Track) Duration() string{
+  return fmt.Sprintf("%d:%02d", t.DurationMs/60000, (t.DurationMs/1000)%60)
+}
+
+func (a Album) ArtistNames() string{
+  return artistNames(a.Artists)
+}
+
+func (c *Client) Search(query string, types []string, limit int, offset int) (SearchResult, error){
+  result := SearchResult{}
+
+  q := url.Values{}
+  q.Set("q", query)
+  q.Set("type", strings.Join(types, ","))
+  q.Set("limit", strconv.Itoa(limit))
+  q.Set("offset", strconv.Itoa(offset))
+
+  err := c.get("/search", q, &result)
+  return result, err
+}
+
+func (c *Client) Track(id string) (Track, error){
+  t := Track{}
+  err := c.get(fmt.Sprintf("/tracks/%s", id), nil, &t)
+  return t, err
+}
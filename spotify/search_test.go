@@ -0,0 +1,95 @@
+package spotify
+
+import(
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "net/http/httptest"
+  "strconv"
+  "strings"
+  "testing"
+)
+
+// Serves searches over a fixed number of tracks, paged the way Spotify does
+func fakeSearch(t *testing.T, total int) *httptest.Server{
+  return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+    if r.URL.Path != "/v1/search"{
+      http.NotFound(w, r)
+      return
+    }
+    q := r.URL.Query()
+    if q.Get("q") != "brightside" || q.Get("type") != "track,album,artist"{
+      t.Errorf("Unexpected search %s", r.URL.RawQuery)
+    }
+    limit, _ := strconv.Atoi(q.Get("limit"))
+    offset, _ := strconv.Atoi(q.Get("offset"))
+
+    result := SearchResult{}
+    result.Tracks.Paging = Paging{Total: total, Limit: limit, Offset: offset}
+    result.Albums.Paging = Paging{Limit: limit, Offset: offset}
+    result.Artists.Paging = Paging{Limit: limit, Offset: offset}
+    for i := offset; i < offset+limit && i < total; i++{
+      result.Tracks.Items = append(result.Tracks.Items, Track{
+        Id: fmt.Sprintf("track%d", i),
+        Uri: fmt.Sprintf("spotify:track:track%d", i),
+        Name: fmt.Sprintf("Track %d", i),
+      })
+    }
+    json.NewEncoder(w).Encode(result)
+  }))
+}
+
+func TestSearchPagination(t *testing.T){
+  server := fakeSearch(t, 25)
+  defer server.Close()
+
+  defer func(apiUrl string){ ApiUrl = apiUrl }(ApiUrl)
+  // The trailing slash is trimmed so paths don't double up
+  ApiUrl = server.URL + "/v1/"
+  client := NewClient(server.Client())
+
+  pages := []struct{
+    offset  int
+    items   int
+    hasNext bool
+  }{
+    {0, 10, true},
+    {10, 10, true},
+    {20, 5, false},
+    {30, 0, false},
+  }
+
+  for _, page := range pages{
+    result, err := client.Search("brightside", []string{"track", "album", "artist"}, 10, page.offset)
+    if err != nil{
+      t.Fatalf("Offset %d: %s", page.offset, err)
+    }
+    if len(result.Tracks.Items) != page.items{
+      t.Errorf("Offset %d: %d tracks, want %d", page.offset, len(result.Tracks.Items), page.items)
+    }
+    if result.HasNext() != page.hasNext{
+      t.Errorf("Offset %d: HasNext = %t, want %t", page.offset, result.HasNext(), page.hasNext)
+    }
+    if result.Empty() != (page.items == 0){
+      t.Errorf("Offset %d: Empty = %t", page.offset, result.Empty())
+    }
+    if page.items > 0 && result.Tracks.Items[0].Id != fmt.Sprintf("track%d", page.offset){
+      t.Errorf("Offset %d: first track %s", page.offset, result.Tracks.Items[0].Id)
+    }
+  }
+}
+
+func TestSearchError(t *testing.T){
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+    w.WriteHeader(401)
+    fmt.Fprint(w, `{"error": {"status": 401, "message": "The access token expired"}}`)
+  }))
+  defer server.Close()
+
+  client := NewClient(server.Client())
+  client.BaseUrl = server.URL
+  _, err := client.Search("brightside", []string{"track"}, 10, 0)
+  if err == nil || !strings.Contains(err.Error(), "Spotify API error: 401"){
+    t.Errorf("Unexpected error %v", err)
+  }
+}
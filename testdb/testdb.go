@@ -0,0 +1,57 @@
+package testdb
+
+import(
+  "github.com/jinzhu/gorm"
+  "github.com/samarudge/jukebox/db"
+  "github.com/samarudge/jukebox/models"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+/*
+  Fixtures for tests that need the database, each test gets a fresh sqlite
+  file with every table migrated
+*/
+
+func Open(t *testing.T) func(){
+  dir, err := ioutil.TempDir("", "jukebox")
+  if err != nil{
+    t.Fatal(err)
+  }
+  if err := db.OpenDB(filepath.Join(dir, "jukebox.db")); err != nil{
+    os.RemoveAll(dir)
+    t.Fatal(err)
+  }
+  models.Migrate()
+  return func(){ os.RemoveAll(dir) }
+}
+
+func User(t *testing.T, a models.Oauth2) models.User{
+  // Signs up the way the login callback does, so the user has an auth record
+  d := db.Db()
+  a.Model = gorm.Model{}
+  d.Create(&a)
+
+  u := models.User{}
+  u.LoginOrSignup(a)
+  if d.NewRecord(u){
+    t.Fatal("Could not create user")
+  }
+  return u
+}
+
+func Room(t *testing.T, owner models.User, name string, uris ...string) models.Room{
+  d := db.Db()
+  r := models.Room{}
+  r.Create(owner, name)
+  if d.NewRecord(r){
+    t.Fatal("Could not create room")
+  }
+
+  for _, uri := range uris{
+    r.Enqueue(owner, &models.QueueEntry{TrackUri: uri, DurationMs: 200000})
+  }
+  return r
+}